@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// backup <file>
+var backupCmd = &cobra.Command{
+	Use:   "backup <file>",
+	Short: "Back up the MicroShift data to a .tar.gz file, the service is stopped meanwhile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := newReporter("backup")
+		err := minc.Backup(cmd.Context(), backupType(args[0]), r)
+		exitOnErr(r, "error backing up cluster", err)
+		if j, ok := r.(*progress.JSON); ok {
+			j.Result(&progress.Result{Success: true})
+			return
+		}
+		log.Info("Backup saved", "file", args[0])
+	},
+}
+
+// restore <file>
+var restoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore the MicroShift data from a file written by backup",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := newReporter("restore")
+		err := minc.Restore(cmd.Context(), backupType(args[0]), r)
+		exitOnErr(r, "error restoring cluster", err)
+		if j, ok := r.(*progress.JSON); ok {
+			j.Result(clusterResult())
+			return
+		}
+		log.Info("Cluster restored", "file", args[0])
+	},
+}
+
+func backupType(file string) *types.BackupType {
+	return &types.BackupType{
+		Provider:            viper.GetString("provider"),
+		File:                file,
+		ServiceWaitTimeout:  viper.GetDuration("service-wait-timeout"),
+		ServiceWaitInterval: viper.GetDuration("service-wait-interval"),
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/minc-org/minc/pkg/certs"
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var certsCmd = &cobra.Command{
+	Use:   "certs",
+	Short: "Manage the cluster's certificates",
+}
+
+// certs trust
+var certsTrustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: fmt.Sprintf("Serve the routes with a certificate of a local CA the host trusts (*.%s)", constants.RouteDomain),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := newReporter("certs trust")
+		ca, err := minc.CertsTrust(cmd.Context(), certsType(), r)
+		exitOnErr(r, "error trusting the router certificate", err)
+		if j, ok := r.(*progress.JSON); ok {
+			j.Result(&progress.Result{Success: true})
+			return
+		}
+		log.Info("The host trusts the route certificates", "ca", ca.CertPath)
+	},
+}
+
+// certs untrust
+var certsUntrustCmd = &cobra.Command{
+	Use:   "untrust",
+	Short: "Remove the local CA from the host trust store and restore the default router certificate",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := newReporter("certs untrust")
+		err := minc.CertsUntrust(cmd.Context(), certsType(), r)
+		exitOnErr(r, "error untrusting the router certificate", err)
+		if j, ok := r.(*progress.JSON); ok {
+			j.Result(&progress.Result{Success: true})
+			return
+		}
+		log.Info("Removed the local CA")
+	},
+}
+
+// certs status
+var certsStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show when the cluster's certificates expire, the first to expire first",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		list, err := minc.CertsStatus(cmd.Context(), viper.GetString("provider"))
+		if err != nil {
+			fatalErr("error reading the certificates", err)
+		}
+		switch certsStatusOutput {
+		case "json":
+			jsonData, err := json.MarshalIndent(list, "", "  ")
+			if err != nil {
+				log.Fatal("error marshalling certificates", "err", err)
+			}
+			fmt.Println(string(jsonData))
+		case "text":
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "EXPIRES\tSTATUS\tSUBJECT\tSOURCE")
+			for _, c := range list {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.NotAfter.Local().Format(time.DateTime), c.Status, c.Subject, c.Source)
+			}
+			w.Flush()
+		default:
+			log.Fatal("output must be text or json", "output", certsStatusOutput)
+		}
+		for _, c := range list {
+			if c.Status == certs.StatusExpired {
+				log.Exit(1)
+			}
+		}
+	},
+}
+
+// certs rotate
+var certsRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Regenerate the MicroShift certificates expiring within 30 days and refresh the kubeconfig",
+	Long: `Regenerate the MicroShift certificates that expired or expire within 30 days
+and refresh the kubeconfig. MicroShift signs the new certificates with its CAs.
+
+--all regenerates the CAs as well: every kubeconfig and client certificate
+signed by the previous CAs stops working, including copies of the kubeconfig.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := newReporter("certs rotate")
+		cType := certsType()
+		cType.All = certsRotateAll
+		rotated, err := minc.CertsRotate(cmd.Context(), cType, r)
+		exitOnErr(r, "error rotating the certificates", err)
+		if j, ok := r.(*progress.JSON); ok {
+			j.Result(clusterResult())
+			return
+		}
+		if len(rotated) == 0 {
+			log.Info("No certificate expires within 30 days, nothing to rotate")
+			return
+		}
+		log.Info("Certificates rotated", "certificates", rotated)
+	},
+}
+
+func certsType() *types.CertsType {
+	return &types.CertsType{
+		Provider:            viper.GetString("provider"),
+		ServiceWaitTimeout:  viper.GetDuration("service-wait-timeout"),
+		ServiceWaitInterval: viper.GetDuration("service-wait-interval"),
+	}
+}
@@ -19,11 +19,17 @@ var configCmd = &cobra.Command{
 var defaultConfig = map[string]interface{}{
 	"provider":              "podman",
 	"log-level":             "info",
+	"log-format":            "text",
 	"microshift-version":    constants.UShiftVersion,
 	"https-port":            "9443",
 	"http-port":             "9080",
 	"microshift-config":     "",
 	"disable-overlay-cache": false,
+	"persistent-data":       false,
+	"cluster-cidr":          "",
+	"service-cidr":          "",
+	"signature-key":         "",
+	"signature-policy":      "",
 	"allow-rootless":        false,
 }
 
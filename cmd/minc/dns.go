@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/minc"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: fmt.Sprintf("Resolve %s and the route hosts without internet DNS", constants.HostName),
+}
+
+// dns hosts [--watch] [--remove]
+var dnsHostsCmd = &cobra.Command{
+	Use:   "hosts",
+	Short: "Add the API server and the route hosts to the hosts file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if dnsRemove {
+			if err := minc.DNSRemoveHosts(dnsHostsFile); err != nil {
+				fatalErr(dnsErrMsg("error removing the hosts file entries", err), err)
+			}
+			fmt.Println("Removed the minc entries from the hosts file")
+			return
+		}
+		err := minc.DNSHosts(cmd.Context(), &types.DNSHostsType{
+			Provider:  viper.GetString("provider"),
+			HostsFile: dnsHostsFile,
+			Watch:     dnsWatch,
+		})
+		if err != nil {
+			fatalErr(dnsErrMsg("error updating the hosts file", err), err)
+		}
+	},
+}
+
+// dns serve [--listen]
+var dnsServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: fmt.Sprintf("Run a DNS server resolving %s and its subdomains to 127.0.0.1", constants.HostName),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := minc.DNSServe(cmd.Context(), dnsListen); err != nil {
+			fatalErr("error serving DNS", err)
+		}
+	},
+}
+
+// dns configure [--listen] [--remove]
+var dnsConfigureCmd = &cobra.Command{
+	Use:   "configure",
+	Short: fmt.Sprintf("Forward the %s queries of the host to 'minc dns serve'", constants.HostName),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if dnsRemove {
+			if err := minc.DNSUnconfigure(cmd.Context()); err != nil {
+				fatalErr(dnsErrMsg("error removing the resolver configuration", err), err)
+			}
+			fmt.Println("Removed the resolver configuration")
+			return
+		}
+		path, err := minc.DNSConfigure(cmd.Context(), dnsListen)
+		if err != nil {
+			fatalErr(dnsErrMsg("error configuring the resolver", err), err)
+		}
+		fmt.Printf("Wrote %s, %s is resolved by the DNS server on %s\n", path, constants.HostName, dnsListen)
+	},
+}
+
+// dnsErrMsg points at sudo when the host's DNS files could not be written
+func dnsErrMsg(msg string, err error) string {
+	if errors.Is(err, os.ErrPermission) {
+		return msg + ", it needs root: run it with sudo"
+	}
+	return msg
+}
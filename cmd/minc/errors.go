@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/providers"
+	"github.com/minc-org/minc/pkg/reference"
+	"github.com/minc-org/minc/pkg/signature"
+)
+
+// Exit codes for well known failures, every other error exits with 1
+const (
+	exitPortAllocated    = 10
+	exitNameInUse        = 11
+	exitImageNotFound    = 12
+	exitPermissionDenied = 13
+	exitTimeout          = 14
+	exitNotRunning       = 15
+	exitDigestMismatch   = 16
+	exitInvalidSignature = 17
+	exitInterrupted      = 130
+)
+
+// knownErrors maps sentinel errors to an actionable hint and exit code
+var knownErrors = []struct {
+	err  error
+	hint string
+	code int
+}{
+	{providers.ErrPortAllocated,
+		"another process or container uses one of the ports, free it or pick others with --http-port/--https-port",
+		exitPortAllocated},
+	{providers.ErrNameInUse,
+		"a container named microshift already exists, remove it with 'minc delete'",
+		exitNameInUse},
+	{providers.ErrImageNotFound,
+		"the MicroShift image does not exist, check --microshift-version and --microshift-image",
+		exitImageNotFound},
+	{providers.ErrPermissionDenied,
+		"the container engine refused the operation, check sudo access or use --allow-rootless, see 'minc doctor'",
+		exitPermissionDenied},
+	{providers.ErrContainerNotRunning,
+		"the MicroShift container stopped, check 'minc logs container'",
+		exitNotRunning},
+	{providers.ErrNoSuchContainer,
+		"the MicroShift container does not exist, use 'minc create' to create it",
+		exitNotRunning},
+	{reference.ErrDigestMismatch,
+		"the MicroShift image does not have the expected digest, check --verify-digest and --microshift-image",
+		exitDigestMismatch},
+	{signature.ErrInvalidSignature,
+		"the MicroShift image signature did not verify, check --signature-key, --signature-bundle and --signature-policy",
+		exitInvalidSignature},
+	{context.DeadlineExceeded,
+		"the operation timed out, increase --timeout or check 'minc logs'",
+		exitTimeout},
+	{context.Canceled,
+		"the operation was interrupted",
+		exitInterrupted},
+}
+
+// classifyErr returns the hint and exit code for err, an empty hint and 1 if
+// it is not a well known failure.
+func classifyErr(err error) (string, int) {
+	for _, k := range knownErrors {
+		if errors.Is(err, k.err) {
+			return k.hint, k.code
+		}
+	}
+	return "", 1
+}
+
+// fatalErr logs err with a hint for well known failures and exits with the
+// matching exit code.
+func fatalErr(msg string, err error) {
+	hint, code := classifyErr(err)
+	if hint == "" {
+		log.Fatal(msg, "err", err)
+	}
+	log.Error(msg, "err", err, "hint", hint)
+	log.Exit(code)
+}
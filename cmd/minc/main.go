@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/hostdns"
 	"github.com/minc-org/minc/pkg/log"
 	"github.com/minc-org/minc/pkg/minc"
 	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/preflight"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/minc-org/minc/pkg/providers"
+	"github.com/minc-org/minc/pkg/providers/register"
 	"github.com/minc-org/minc/pkg/rootlessmarker"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -19,6 +28,8 @@ import (
 var (
 	provider            string
 	logLevel            string
+	logFormat           string
+	logFile             string
 	uShiftVersion       string
 	uShiftConfig        string
 	httpsPort           string
@@ -26,63 +37,207 @@ var (
 	uShiftImage         string
 	disableOverlayCache bool
 	allowRootless       bool
+	logsFollow          bool
+	logsSince           string
+	logsTail            int
+	diagnoseOutput      string
+	doctorOutput        string
+	skipPreflight       bool
+	createTimeout       time.Duration
+	keepOnFailure       bool
+	serviceWaitTimeout  time.Duration
+	serviceWaitInterval time.Duration
+	quiet               bool
+	lifecycleOutput     string
+	fromSnapshot        string
+	verifyDigest        string
+	signatureKey        string
+	signatureBundle     string
+	signaturePolicy     string
+	networkSubnet       string
+	networkIP           string
+	clusterCIDR         string
+	serviceCIDR         string
+	apiSANs             []string
+	snapshotListOutput  string
+	persistentData      bool
+	deleteKeepData      bool
+	deletePurge         bool
+	upgradeVersion      string
+	upgradeImage        string
+	upgradeForce        bool
+	upgradeTimeout      time.Duration
+	upgradeReadyTimeout time.Duration
+	upgradeDigest       string
+	upgradeBundle       string
+	versionsImage       string
+	versionsPlainHTTP   bool
+	versionsOutput      string
+	dnsHostsFile        string
+	dnsWatch            bool
+	dnsRemove           bool
+	dnsListen           string
+	routesNamespace     string
+	routesOpen          bool
+	routesOutput        string
+	certsStatusOutput   string
+	certsRotateAll      bool
 )
 
 var createCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create the MicroShift cluster",
 	Run: func(cmd *cobra.Command, args []string) {
-		uShiftConf := viper.GetString("microshift-config")
-		if uShiftConf != "" {
-			_, err := os.Stat(uShiftConf)
-			if os.IsNotExist(err) {
-				log.Fatal("config file does not exist", "Config", uShiftConf)
-			}
-		}
-		hPort, err := strconv.Atoi(viper.GetString("http-port"))
-		if err != nil {
-			log.Fatal("http port must be an integer", "port", viper.GetString("http-port"))
-		}
-		hsPort, err := strconv.Atoi(viper.GetString("https-port"))
-		if err != nil {
-			log.Fatal("https port must be an integer", "port", viper.GetString("https-port"))
-		}
-
-		cType := &types.CreateType{
-			Provider:            viper.GetString("provider"),
-			UShiftVersion:       viper.GetString("microshift-version"),
-			UShiftImage:         viper.GetString("microshift-image"),
-			UShiftConfig:        uShiftConf,
-			HTTPSPort:           hsPort,
-			HTTPPort:            hPort,
-			DisableOverlayCache: viper.GetBool("disable-overlay-cache"),
-		}
+		cType := createType()
+		cType.FromSnapshot = fromSnapshot
+		cType.VerifyDigest = verifyDigest
+		cType.SignatureBundle = signatureBundle
 		allowRL := viper.GetBool("allow-rootless")
 		if allowRL {
 			if err := rootlessmarker.Set(); err != nil {
 				log.Fatal("failed to record rootless mode", "err", err)
 			}
 		}
-		err = minc.Create(cType)
+		ctx := cmd.Context()
+		if createTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, createTimeout)
+			defer cancel()
+		}
+		r := newReporter("create")
+		err := minc.Create(ctx, cType, r)
 		if err != nil {
 			if allowRL {
 				if rmErr := rootlessmarker.Remove(); rmErr != nil {
 					log.Error("failed to clear rootless marker after create failure", "err", rmErr)
 				}
 			}
-			log.Fatal("error creating cluster", "err", err)
+			exitOnErr(r, "error creating cluster", err)
+		}
+		if j, ok := r.(*progress.JSON); ok {
+			j.Result(createResult(cType.HTTPPort, cType.HTTPSPort))
+			return
 		}
 		log.Info("Cluster created")
 	},
 }
 
+// createType returns the create settings from the flags and config
+func createType() *types.CreateType {
+	uShiftConf := viper.GetString("microshift-config")
+	if uShiftConf != "" {
+		_, err := os.Stat(uShiftConf)
+		if os.IsNotExist(err) {
+			log.Fatal("config file does not exist", "Config", uShiftConf)
+		}
+	}
+	hPort, hsPort := routePorts()
+	return &types.CreateType{
+		Provider:            viper.GetString("provider"),
+		UShiftVersion:       viper.GetString("microshift-version"),
+		UShiftImage:         viper.GetString("microshift-image"),
+		UShiftConfig:        uShiftConf,
+		HTTPSPort:           hsPort,
+		HTTPPort:            hPort,
+		DisableOverlayCache: viper.GetBool("disable-overlay-cache"),
+		PersistentData:      viper.GetBool("persistent-data"),
+		Network:             constants.NetworkName,
+		Subnet:              networkSubnet,
+		IP:                  networkIP,
+		ClusterCIDR:         viper.GetString("cluster-cidr"),
+		ServiceCIDR:         viper.GetString("service-cidr"),
+		APISANs:             apiSANs,
+		SignatureKey:        viper.GetString("signature-key"),
+		SignaturePolicy:     viper.GetString("signature-policy"),
+		SkipPreflight:       skipPreflight,
+		KeepOnFailure:       keepOnFailure,
+		ServiceWaitTimeout:  viper.GetDuration("service-wait-timeout"),
+		ServiceWaitInterval: viper.GetDuration("service-wait-interval"),
+	}
+}
+
+// routePorts returns the configured http and https route ports
+func routePorts() (int, int) {
+	hPort, err := strconv.Atoi(viper.GetString("http-port"))
+	if err != nil {
+		log.Fatal("http port must be an integer", "port", viper.GetString("http-port"))
+	}
+	hsPort, err := strconv.Atoi(viper.GetString("https-port"))
+	if err != nil {
+		log.Fatal("https port must be an integer", "port", viper.GetString("https-port"))
+	}
+	return hPort, hsPort
+}
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the MicroShift cluster in place, keeping its data",
+	Run: func(cmd *cobra.Command, args []string) {
+		uType := &types.UpgradeType{
+			Provider:            viper.GetString("provider"),
+			UShiftVersion:       upgradeVersion,
+			UShiftImage:         upgradeImage,
+			Force:               upgradeForce,
+			ReadyTimeout:        upgradeReadyTimeout,
+			VerifyDigest:        upgradeDigest,
+			SignatureBundle:     upgradeBundle,
+			ServiceWaitTimeout:  viper.GetDuration("service-wait-timeout"),
+			ServiceWaitInterval: viper.GetDuration("service-wait-interval"),
+		}
+		ctx := cmd.Context()
+		if upgradeTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, upgradeTimeout)
+			defer cancel()
+		}
+		r := newReporter("upgrade")
+		err := minc.Upgrade(ctx, uType, r)
+		exitOnErr(r, "error upgrading cluster", err)
+		if j, ok := r.(*progress.JSON); ok {
+			j.Result(clusterResult())
+			return
+		}
+		log.Info("Cluster upgraded", "version", upgradeVersion)
+	},
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the host and container engine are ready to run MicroShift",
+	Run: func(cmd *cobra.Command, args []string) {
+		hPort, hsPort := routePorts()
+		results := minc.Doctor(cmd.Context(), &types.DoctorType{
+			Provider:      viper.GetString("provider"),
+			UShiftVersion: viper.GetString("microshift-version"),
+			UShiftImage:   viper.GetString("microshift-image"),
+			HTTPSPort:     hsPort,
+			HTTPPort:      hPort,
+			ClusterCIDR:   viper.GetString("cluster-cidr"),
+			ServiceCIDR:   viper.GetString("service-cidr"),
+		})
+		switch doctorOutput {
+		case "json":
+			if err := preflight.PrintJSON(os.Stdout, results); err != nil {
+				log.Fatal("error marshalling results", "err", err)
+			}
+		case "text":
+			preflight.Print(os.Stdout, results)
+		default:
+			log.Fatal("output must be text or json", "output", doctorOutput)
+		}
+		if preflight.Failed(results) != nil {
+			log.Exit(1)
+		}
+	},
+}
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List the MicroShift cluster",
 	Run: func(cmd *cobra.Command, args []string) {
-		ls, err := minc.List(viper.GetString("provider"))
+		ls, err := minc.List(cmd.Context(), viper.GetString("provider"))
 		if err != nil {
-			log.Fatal("error listing cluster", "err", err)
+			fatalErr("error listing cluster", err)
 		}
 		fmt.Printf("%s", ls)
 	},
@@ -92,7 +247,7 @@ var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Status of MicroShift cluster",
 	Run: func(cmd *cobra.Command, args []string) {
-		status := minc.Status(viper.GetString("provider"))
+		status := minc.Status(cmd.Context(), viper.GetString("provider"))
 		jsonData, err := json.MarshalIndent(status, "", "  ")
 		if err != nil {
 			log.Fatal("error marshalling status", "err", err)
@@ -105,12 +260,19 @@ var deleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Delete the MicroShift cluster",
 	Run: func(cmd *cobra.Command, args []string) {
-		err := minc.Delete(viper.GetString("provider"))
-		if err != nil {
-			log.Fatal("error deleting cluster", "err", err)
-		}
+		r := newReporter("delete")
+		err := minc.Delete(cmd.Context(), &types.DeleteType{
+			Provider: viper.GetString("provider"),
+			KeepData: deleteKeepData,
+			Purge:    deletePurge,
+		}, r)
+		exitOnErr(r, "error deleting cluster", err)
 		if err := rootlessmarker.Remove(); err != nil {
-			fmt.Fprintf(os.Stderr, "warning: deleted cluster but failed to clear rootless marker: %v\n", err)
+			r.Warn(fmt.Sprintf("deleted cluster but failed to clear rootless marker: %v", err))
+		}
+		if j, ok := r.(*progress.JSON); ok {
+			j.Result(&progress.Result{Success: true})
+			return
 		}
 		fmt.Println("Item deleted")
 	},
@@ -120,14 +282,57 @@ var generateKubeConfig = &cobra.Command{
 	Use:   "generate-kubeconfig",
 	Short: "generate the kubeconfig for MicroShift cluster",
 	Run: func(cmd *cobra.Command, args []string) {
-		err := minc.GenerateKubeConfig(viper.GetString("provider"))
+		err := minc.GenerateKubeConfig(cmd.Context(), viper.GetString("provider"))
 		if err != nil {
-			log.Fatal("error generating kubeconfig file", "err", err)
+			fatalErr("error generating kubeconfig file", err)
 		}
 		fmt.Println("kubeconfig generated and context is added to default config")
 	},
 }
 
+var logsCmd = &cobra.Command{
+	Use:       "logs [microshift|crio|container]",
+	Short:     "Show MicroShift, CRI-O or container logs",
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"microshift", "crio", "container"},
+	Run: func(cmd *cobra.Command, args []string) {
+		component := "microshift"
+		if len(args) == 1 {
+			component = args[0]
+		}
+		lType := &types.LogsType{
+			Provider:  viper.GetString("provider"),
+			Component: component,
+			Follow:    logsFollow,
+			Since:     logsSince,
+			Tail:      logsTail,
+		}
+		if err := minc.Logs(cmd.Context(), lType); err != nil {
+			fatalErr("error reading logs", err)
+		}
+	},
+}
+
+var diagnoseCmd = &cobra.Command{
+	Use:   "diagnose",
+	Short: "Collect a support bundle (.tar.gz) for bug reports",
+	Run: func(cmd *cobra.Command, args []string) {
+		output := diagnoseOutput
+		if output == "" {
+			output = fmt.Sprintf("minc-diagnose-%s.tar.gz", time.Now().Format("20060102-150405"))
+		}
+		dType := &types.DiagnoseType{
+			Provider: viper.GetString("provider"),
+			Output:   output,
+			Config:   viper.AllSettings(),
+		}
+		if err := minc.Diagnose(cmd.Context(), dType); err != nil {
+			log.Fatal("error collecting diagnostics", "err", err)
+		}
+		fmt.Printf("Diagnostics bundle written to %s\n", output)
+	},
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show the version of minc",
@@ -136,6 +341,15 @@ var versionCmd = &cobra.Command{
 	},
 }
 
+// runLogDir is the directory holding the per-run debug logs
+func runLogDir() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "minc", "logs")
+}
+
 func initConfig() {
 	appName := "minc"
 	configFileName := "config.json"
@@ -158,14 +372,14 @@ func initConfig() {
 			fmt.Println("Error creating config file:", err)
 			os.Exit(1)
 		}
-		fmt.Println("Created empty config file: ", configFilePath)
+		fmt.Fprintln(os.Stderr, "Created empty config file: ", configFilePath)
 	}
 
 	viper.SetConfigFile(configFilePath)
 	// Set defaults from shared config
 	for key, value := range defaultConfig {
 		// Only set viper defaults for non-flag values (flags set their own defaults)
-		if key != "https-port" && key != "http-port" && key != "disable-overlay-cache" {
+		if key != "https-port" && key != "http-port" && key != "disable-overlay-cache" && key != "persistent-data" {
 			viper.SetDefault(key, value)
 		}
 	}
@@ -181,9 +395,13 @@ func main() {
 		Use:   "minc",
 		Short: "MicroShift in Container",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// Set logger based on user-provided log level
-			log.SetLogger(viper.GetString("log-level"))
-			return nil
+			// Set logger based on user-provided log level, format and file
+			return log.SetLogger(&log.Options{
+				Level:     viper.GetString("log-level"),
+				Format:    viper.GetString("log-format"),
+				File:      viper.GetString("log-file"),
+				RunLogDir: runLogDir(),
+			})
 		},
 	}
 
@@ -191,41 +409,181 @@ func main() {
 
 	// create command flags
 	createCmd.PersistentFlags().StringVarP(&uShiftVersion, "microshift-version", "m", "",
-		fmt.Sprintf("MicroShift version to use, check available tag %s", constants.GetImageRegistry()))
+		"MicroShift version to use, see 'minc versions' for the available ones")
 	createCmd.PersistentFlags().StringVarP(&uShiftImage, "microshift-image", "i", "",
-		"MicroShift image to use if default image registry is not to be used")
+		"MicroShift image repository to use instead of the default one, an image with a tag or @sha256: digest is used as is")
+	createCmd.PersistentFlags().StringVar(&networkSubnet, "subnet", "",
+		fmt.Sprintf("Subnet of the %s network the cluster runs on, e.g. 10.89.10.0/24 (default: chosen by the engine)", constants.NetworkName))
+	createCmd.PersistentFlags().StringVar(&networkIP, "ip", "",
+		"Static IP of the MicroShift container in --subnet")
+	createCmd.PersistentFlags().StringVar(&clusterCIDR, "cluster-cidr", "",
+		fmt.Sprintf("MicroShift pod network, e.g. to avoid VPN routes (default: %s)", constants.DefaultClusterCIDR))
+	createCmd.PersistentFlags().StringVar(&serviceCIDR, "service-cidr", "",
+		fmt.Sprintf("MicroShift service network (default: %s)", constants.DefaultServiceCIDR))
+	createCmd.PersistentFlags().StringArrayVar(&apiSANs, "api-san", nil,
+		"Extra name or IP the API server certificate is valid for, e.g. a LAN IP; repeatable. Publishes the API server on all interfaces")
+	createCmd.PersistentFlags().StringVar(&verifyDigest, "verify-digest", "",
+		"Fail unless the pulled MicroShift image has this digest, e.g. sha256:<hex>")
+	createCmd.PersistentFlags().StringVar(&signatureKey, "signature-key", "",
+		"Public key (PEM) verifying the image's cosign signature, requires --signature-bundle")
+	createCmd.PersistentFlags().StringVar(&signatureBundle, "signature-bundle", "",
+		"The image's cosign signature, as written by 'cosign download signature'")
+	createCmd.PersistentFlags().StringVar(&signaturePolicy, "signature-policy", "",
+		"containers-policy.json(5) file the image is pulled with, podman only")
 	createCmd.PersistentFlags().StringVarP(&uShiftConfig, "microshift-config", "c", "",
 		"MicroShift custom config file")
 	createCmd.PersistentFlags().StringVar(&httpsPort, "https-port", defaultConfig["https-port"].(string),
 		fmt.Sprintf("https route port to be exposed by container (default: %s)", defaultConfig["https-port"]))
 	createCmd.PersistentFlags().StringVar(&httpPort, "http-port", defaultConfig["http-port"].(string),
 		fmt.Sprintf("http route port to be exposed by container (default: %s)", defaultConfig["http-port"]))
+	createCmd.PersistentFlags().DurationVar(&createTimeout, "timeout", 0,
+		"Maximum time to wait for the cluster to be created, e.g. 10m (default: no timeout)")
+	createCmd.PersistentFlags().DurationVar(&serviceWaitTimeout, "service-wait-timeout", providers.DefaultServiceWaitTimeout,
+		"Maximum time to wait for the MicroShift service to become active")
+	createCmd.PersistentFlags().DurationVar(&serviceWaitInterval, "service-wait-interval", providers.DefaultServiceWaitInterval,
+		"Initial delay between MicroShift service checks, it grows exponentially")
+	createCmd.PersistentFlags().BoolVar(&keepOnFailure, "keep-on-failure", false,
+		"Keep the partially created cluster when create fails instead of rolling it back, for debugging")
+	createCmd.PersistentFlags().BoolVar(&persistentData, "persistent-data", defaultConfig["persistent-data"].(bool),
+		fmt.Sprintf("Keep MicroShift's data (%s) on the %s volume, it survives recreating the container", constants.UShiftDataDir, constants.DataVolume))
+	createCmd.PersistentFlags().StringVar(&fromSnapshot, "from-snapshot", "",
+		"Create the cluster from a snapshot, see 'minc snapshot', its settings replace the create flags")
+	createCmd.PersistentFlags().BoolVar(&skipPreflight, "skip-preflight", false,
+		"Skip the preflight checks run before creating the cluster, see 'minc doctor'")
 	createCmd.PersistentFlags().BoolVar(&disableOverlayCache, "disable-overlay-cache", defaultConfig["disable-overlay-cache"].(bool),
 		"Disable container overlay storage cache mount for better isolation and macOS Docker compatibility")
 
+	// the commands changing the cluster report JSON events with --output json
+	for _, c := range []*cobra.Command{createCmd, deleteCmd, upgradeCmd, snapshotSaveCmd, snapshotRestoreCmd, backupCmd, restoreCmd, certsTrustCmd, certsUntrustCmd, certsRotateCmd} {
+		c.Flags().StringVarP(&lifecycleOutput, "output", "o", "text",
+			"Output format: text, or json for newline delimited JSON events on stdout")
+	}
+
+	// logs command flags
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Follow log output")
+	logsCmd.Flags().StringVar(&logsSince, "since", "",
+		"Show logs since timestamp (e.g. 2025-01-02 15:04:05) or relative time (e.g. 10m)")
+	logsCmd.Flags().IntVarP(&logsTail, "tail", "n", 0, "Number of lines to show from the end of the logs (default: all)")
+
+	// doctor command flags
+	doctorCmd.Flags().StringVarP(&doctorOutput, "output", "o", "text", "Output format: text or json")
+
+	// diagnose command flags
+	diagnoseCmd.Flags().StringVarP(&diagnoseOutput, "output", "o", "",
+		"Path of the support bundle (default: minc-diagnose-<timestamp>.tar.gz)")
+
 	rootCmd.PersistentFlags().StringVarP(&provider, "provider", "p", "", "Specify the provider (e.g., podman, docker)")
 	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "", "Log level (e.g., info, debug, warn)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "Log format: text or json (default: text)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "",
+		"File capturing the debug log (default: a per-run file in the minc config directory's logs folder)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false,
+		"Do not report progress, only warnings and errors")
 	rootCmd.PersistentFlags().BoolVar(&allowRootless, "allow-rootless", defaultConfig["allow-rootless"].(bool),
 		"Use rootless Podman (no sudo); experimental — MicroShift may not start")
 
+	// upgrade command flags
+	upgradeCmd.Flags().StringVarP(&upgradeVersion, "microshift-version", "m", "", "MicroShift version to upgrade to")
+	upgradeCmd.Flags().StringVarP(&upgradeImage, "microshift-image", "i", "",
+		"MicroShift image repository to upgrade to (default: the cluster's)")
+	upgradeCmd.Flags().BoolVar(&upgradeForce, "force", false,
+		"Upgrade even across version skews MicroShift does not support")
+	upgradeCmd.Flags().DurationVar(&upgradeTimeout, "timeout", 0,
+		"Maximum time the upgrade may take, e.g. 15m (default: no timeout)")
+	upgradeCmd.Flags().DurationVar(&upgradeReadyTimeout, "ready-timeout", minc.DefaultUpgradeReadyTimeout,
+		"Maximum time the upgraded cluster may take to get ready before the previous version is restored")
+	upgradeCmd.Flags().StringVar(&upgradeDigest, "verify-digest", "",
+		"Fail unless the pulled MicroShift image has this digest, e.g. sha256:<hex>")
+	upgradeCmd.Flags().StringVar(&upgradeBundle, "signature-bundle", "",
+		"The new image's cosign signature, required when the cluster was created with --signature-key")
+	upgradeCmd.MarkFlagRequired("microshift-version")
+
+	// delete command flags
+	deleteCmd.Flags().BoolVar(&deleteKeepData, "keep-data", false,
+		fmt.Sprintf("Keep the %s volume for the next 'create --persistent-data'", constants.DataVolume))
+	deleteCmd.Flags().BoolVar(&deletePurge, "purge", false,
+		fmt.Sprintf("Also delete the %s volume and every other volume of the container", constants.StorageVolume))
+	deleteCmd.MarkFlagsMutuallyExclusive("keep-data", "purge")
+
+	// snapshot command flags
+	snapshotListCmd.Flags().StringVarP(&snapshotListOutput, "output", "o", "text", "Output format: text or json")
+
+	// versions command flags
+	versionsCmd.Flags().StringVarP(&versionsImage, "microshift-image", "i", "",
+		"MicroShift image repository to list (default: the configured one or "+constants.GetImageRegistry()+")")
+	versionsCmd.Flags().BoolVar(&versionsPlainHTTP, "plain-http", false,
+		"Query the registry over http instead of https, e.g. a local registry")
+	versionsCmd.Flags().StringVarP(&versionsOutput, "output", "o", "text", "Output format: text or json")
+
+	// dns command flags
+	dnsHostsCmd.Flags().StringVar(&dnsHostsFile, "hosts-file", "",
+		"Hosts file to manage (default: "+hostdns.DefaultHostsFile()+")")
+	dnsHostsCmd.Flags().BoolVarP(&dnsWatch, "watch", "w", false,
+		"Keep the hosts file in sync with the routes until interrupted")
+	for _, c := range []*cobra.Command{dnsHostsCmd, dnsConfigureCmd} {
+		c.Flags().BoolVar(&dnsRemove, "remove", false, "Remove what the command added")
+	}
+	dnsHostsCmd.MarkFlagsMutuallyExclusive("watch", "remove")
+	for _, c := range []*cobra.Command{dnsServeCmd, dnsConfigureCmd} {
+		c.Flags().StringVar(&dnsListen, "listen", hostdns.DefaultListen, "Address of the DNS server")
+	}
+
+	// routes command flags
+	routesCmd.Flags().StringVarP(&routesNamespace, "namespace", "n", "", "Namespace of the routes (default: all namespaces)")
+	routesCmd.Flags().BoolVar(&routesOpen, "open", false, "Open the route's URL in the browser")
+	routesCmd.Flags().StringVarP(&routesOutput, "output", "o", "text", "Output format: text or json")
+
+	// certs command flags
+	certsStatusCmd.Flags().StringVarP(&certsStatusOutput, "output", "o", "text", "Output format: text or json")
+	certsRotateCmd.Flags().BoolVar(&certsRotateAll, "all", false,
+		"Regenerate the CAs and all certificates, invalidating every kubeconfig they signed")
+
 	// Add config subcommands
 	configCmd.AddCommand(configSetCmd, configGetCmd, configUnsetCmd, configViewCmd)
+	snapshotCmd.AddCommand(snapshotSaveCmd, snapshotRestoreCmd, snapshotListCmd, snapshotDeleteCmd)
+	networkCmd.AddCommand(networkConnectCmd, networkDisconnectCmd)
+	dnsCmd.AddCommand(dnsHostsCmd, dnsServeCmd, dnsConfigureCmd)
+	certsCmd.AddCommand(certsTrustCmd, certsUntrustCmd, certsStatusCmd, certsRotateCmd)
 
-	rootCmd.AddCommand(createCmd, listCmd, deleteCmd, versionCmd, statusCmd, generateKubeConfig, configCmd)
+	rootCmd.AddCommand(createCmd, listCmd, deleteCmd, versionCmd, statusCmd, generateKubeConfig, configCmd, logsCmd, diagnoseCmd, doctorCmd, snapshotCmd, upgradeCmd, backupCmd, restoreCmd, versionsCmd, networkCmd, dnsCmd, routesCmd, certsCmd)
 
 	// Binding with viper
 	viper.BindPFlag("provider", rootCmd.PersistentFlags().Lookup("provider"))
 	viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level"))
+	viper.BindPFlag("log-format", rootCmd.PersistentFlags().Lookup("log-format"))
+	viper.BindPFlag("log-file", rootCmd.PersistentFlags().Lookup("log-file"))
 	viper.BindPFlag("allow-rootless", rootCmd.PersistentFlags().Lookup("allow-rootless"))
 	viper.BindPFlag("microshift-version", createCmd.PersistentFlags().Lookup("microshift-version"))
 	viper.BindPFlag("microshift-image", createCmd.PersistentFlags().Lookup("microshift-image"))
 	viper.BindPFlag("microshift-config", createCmd.PersistentFlags().Lookup("microshift-config"))
+	viper.BindPFlag("signature-key", createCmd.PersistentFlags().Lookup("signature-key"))
+	viper.BindPFlag("signature-policy", createCmd.PersistentFlags().Lookup("signature-policy"))
 	viper.BindPFlag("https-port", createCmd.PersistentFlags().Lookup("https-port"))
 	viper.BindPFlag("http-port", createCmd.PersistentFlags().Lookup("http-port"))
 	viper.BindPFlag("disable-overlay-cache", createCmd.PersistentFlags().Lookup("disable-overlay-cache"))
+	viper.BindPFlag("persistent-data", createCmd.PersistentFlags().Lookup("persistent-data"))
+	viper.BindPFlag("cluster-cidr", createCmd.PersistentFlags().Lookup("cluster-cidr"))
+	viper.BindPFlag("service-cidr", createCmd.PersistentFlags().Lookup("service-cidr"))
+	viper.BindPFlag("service-wait-timeout", createCmd.PersistentFlags().Lookup("service-wait-timeout"))
+	viper.BindPFlag("service-wait-interval", createCmd.PersistentFlags().Lookup("service-wait-interval"))
 
-	if err := rootCmd.Execute(); err != nil {
+	// Ctrl-C and SIGTERM cancel the running command, which stops cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// commands failing with log.Fatal exit before ExecuteContext returns
+	log.OnExit(closeRunner)
+	err := rootCmd.ExecuteContext(ctx)
+	if err != nil {
 		fmt.Println("Error executing command: ", err)
-		os.Exit(1)
+		log.Exit(1)
+	}
+	closeRunner()
+}
+
+// closeRunner closes the command runner, flushing the MINC_EXEC_RECORD
+// transcript
+func closeRunner() {
+	if err := register.Close(); err != nil {
+		log.Warn("failed to close the command runner", "err", err)
 	}
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/minc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Attach other containers to the cluster's network",
+}
+
+// network connect <container>
+var networkConnectCmd = &cobra.Command{
+	Use:   "connect <container>",
+	Short: fmt.Sprintf("Connect a container to the %s network, it reaches the cluster as %s", constants.NetworkName, constants.ContainerName),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := minc.NetworkConnect(cmd.Context(), viper.GetString("provider"), args[0]); err != nil {
+			fatalErr("error connecting container", err)
+		}
+		fmt.Printf("Container %s connected to the %s network\n", args[0], constants.NetworkName)
+	},
+}
+
+// network disconnect <container>
+var networkDisconnectCmd = &cobra.Command{
+	Use:   "disconnect <container>",
+	Short: fmt.Sprintf("Disconnect a container from the %s network", constants.NetworkName),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := minc.NetworkDisconnect(cmd.Context(), viper.GetString("provider"), args[0]); err != nil {
+			fatalErr("error disconnecting container", err)
+		}
+		fmt.Printf("Container %s disconnected from the %s network\n", args[0], constants.NetworkName)
+	},
+}
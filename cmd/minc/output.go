@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/minc-org/minc/pkg/clusterstate"
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/kubeconfig"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/progress"
+)
+
+// newReporter returns the progress reporter for a lifecycle command's
+// --output: human progress on stderr, or JSON events on stdout.
+func newReporter(command string) progress.Reporter {
+	switch lifecycleOutput {
+	case "json":
+		return progress.NewJSON(os.Stdout, command)
+	case "text":
+		return progress.New(os.Stderr, quiet)
+	default:
+		log.Fatal("output must be text or json", "output", lifecycleOutput)
+		return nil
+	}
+}
+
+// exitOnErr ends a lifecycle command that failed with err, sending the
+// result event first when reporting JSON.
+func exitOnErr(r progress.Reporter, msg string, err error) {
+	if err == nil {
+		return
+	}
+	if j, ok := r.(*progress.JSON); ok {
+		hint, code := classifyErr(err)
+		j.Result(&progress.Result{
+			ExitCode: code,
+			Error:    fmt.Sprintf("%s: %v", msg, err),
+			Hint:     hint,
+		})
+	}
+	fatalErr(msg, err)
+}
+
+// createResult is the result event of a successful create
+func createResult(httpPort, httpsPort int) *progress.Result {
+	res := &progress.Result{
+		Success: true,
+		Endpoints: &progress.Endpoints{
+			HTTP:  routeURL("http", httpPort),
+			HTTPS: routeURL("https", httpsPort),
+		},
+	}
+	entry, err := kubeconfig.GetEntry()
+	if err != nil {
+		log.Warn("failed to read the cluster kubeconfig entry", "err", err)
+		return res
+	}
+	res.KubeConfig, res.Context, res.Endpoints.API = entry.Path, entry.Context, entry.Server
+	return res
+}
+
+// clusterResult is the result event of a successful command changing the
+// existing cluster, with the route ports recorded when it was created
+func clusterResult() *progress.Result {
+	state, err := clusterstate.Load()
+	if err != nil {
+		log.Fatal("error reading the cluster settings", "err", err)
+	}
+	return createResult(state.Cluster.HTTPPort, state.Cluster.HTTPSPort)
+}
+
+// routeURL is the host side URL routes are served on, without the port when
+// it is the scheme's default
+func routeURL(scheme string, port int) string {
+	if (scheme == "http" && port == 80) || (scheme == "https" && port == 443) {
+		return fmt.Sprintf("%s://*.%s", scheme, constants.RouteDomain)
+	}
+	return fmt.Sprintf("%s://*.%s:%d", scheme, constants.RouteDomain, port)
+}
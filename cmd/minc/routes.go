@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/minc-org/minc/pkg/exec"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var routesCmd = &cobra.Command{
+	Use:   "routes [name]",
+	Short: "List the OpenShift routes and the URLs they are reached on",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		rType := &types.RoutesType{
+			Provider:  viper.GetString("provider"),
+			Namespace: routesNamespace,
+		}
+		if len(args) == 1 {
+			rType.Name = args[0]
+		}
+		routes, err := minc.Routes(cmd.Context(), rType)
+		if err != nil {
+			fatalErr("error listing routes", err)
+		}
+		if routesOpen {
+			openRoute(cmd.Context(), routes)
+			return
+		}
+		switch routesOutput {
+		case "json":
+			jsonData, err := json.MarshalIndent(routes, "", "  ")
+			if err != nil {
+				log.Fatal("error marshalling routes", "err", err)
+			}
+			fmt.Println(string(jsonData))
+		case "text":
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "NAMESPACE\tNAME\tSERVICE\tURL")
+			for _, r := range routes {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Namespace, r.Name, r.Service, r.URL)
+			}
+			w.Flush()
+		default:
+			log.Fatal("output must be text or json", "output", routesOutput)
+		}
+	},
+}
+
+// openRoute opens the URL of the only route in the browser
+func openRoute(ctx context.Context, routes []types.RouteURL) {
+	switch {
+	case len(routes) == 0:
+		log.Fatal("no route to open")
+	case len(routes) > 1:
+		names := make([]string, 0, len(routes))
+		for _, r := range routes {
+			names = append(names, r.Namespace+"/"+r.Name)
+		}
+		log.Fatal("more than one route, name the one to open", "routes", strings.Join(names, ", "))
+	case routes[0].URL == "":
+		log.Fatal("the router port of the route is not published", "route", routes[0].Name)
+	}
+	url := routes[0].URL
+	var cmd exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "open", url)
+	case "windows":
+		cmd = exec.CommandContext(ctx, "rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.CommandContext(ctx, "xdg-open", url)
+	}
+	if err := cmd.Run(); err != nil {
+		log.Fatal("error opening the browser, open the URL manually", "url", url, "err", err)
+	}
+	fmt.Printf("Opened %s\n", url)
+}
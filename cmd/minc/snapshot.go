@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and restore snapshots of the MicroShift cluster",
+}
+
+// snapshot save <name>
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Snapshot the cluster, it is stopped while the snapshot is taken",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r := newReporter("snapshot-save")
+		err := minc.SnapshotSave(cmd.Context(), viper.GetString("provider"), args[0], r)
+		exitOnErr(r, "error saving snapshot", err)
+		if j, ok := r.(*progress.JSON); ok {
+			j.Result(&progress.Result{Success: true})
+			return
+		}
+		log.Info("Snapshot saved", "name", args[0])
+	},
+}
+
+// snapshot restore <name>
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Replace the cluster with one created from a snapshot",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cType := createType()
+		cType.FromSnapshot = args[0]
+		r := newReporter("snapshot-restore")
+		err := minc.SnapshotRestore(cmd.Context(), cType, r)
+		exitOnErr(r, "error restoring snapshot", err)
+		if j, ok := r.(*progress.JSON); ok {
+			j.Result(createResult(cType.HTTPPort, cType.HTTPSPort))
+			return
+		}
+		log.Info("Cluster restored", "snapshot", args[0])
+	},
+}
+
+// snapshot list
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the snapshots",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		snapshots, err := minc.SnapshotList()
+		if err != nil {
+			log.Fatal("error listing snapshots", "err", err)
+		}
+		switch snapshotListOutput {
+		case "json":
+			jsonData, err := json.MarshalIndent(snapshots, "", "  ")
+			if err != nil {
+				log.Fatal("error marshalling snapshots", "err", err)
+			}
+			fmt.Println(string(jsonData))
+		case "text":
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "NAME\tCREATED\tPROVIDER\tSOURCE IMAGE\tVOLUMES SIZE")
+			for _, s := range snapshots {
+				var size int64
+				for _, v := range s.Volumes {
+					size += v.Size
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.Name, s.Created.Format(time.DateTime),
+					s.Provider, s.SourceImage, humanSize(size))
+			}
+			w.Flush()
+		default:
+			log.Fatal("output must be text or json", "output", snapshotListOutput)
+		}
+	},
+}
+
+// snapshot delete <name>
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a snapshot and its image",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := minc.SnapshotDelete(cmd.Context(), args[0]); err != nil {
+			fatalErr("error deleting snapshot", err)
+		}
+		fmt.Printf("Snapshot %s deleted\n", args[0])
+	},
+}
+
+// humanSize formats a size in bytes with a binary unit
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var versionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "List the MicroShift versions available in the image registry",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		image := versionsImage
+		if image == "" {
+			image = viper.GetString("microshift-image")
+		}
+		versions, err := minc.Versions(cmd.Context(), &types.VersionsType{
+			Provider:    viper.GetString("provider"),
+			UShiftImage: image,
+			PlainHTTP:   versionsPlainHTTP,
+		})
+		if err != nil {
+			fatalErr("error listing versions", err)
+		}
+		switch versionsOutput {
+		case "json":
+			jsonData, err := json.MarshalIndent(versions, "", "  ")
+			if err != nil {
+				log.Fatal("error marshalling versions", "err", err)
+			}
+			fmt.Println(string(jsonData))
+		case "text":
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tDEFAULT\tLOCAL")
+			for _, v := range versions {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", v.Version, mark(v.Default), mark(v.Local))
+			}
+			w.Flush()
+		default:
+			log.Fatal("output must be text or json", "output", versionsOutput)
+		}
+	},
+}
+
+func mark(b bool) string {
+	if b {
+		return "*"
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/minc-org/minc/pkg/constants"
+)
+
+func TestIssueNameConstraints(t *testing.T) {
+	ca, err := createCA(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	tests := []struct {
+		name    string
+		dnsName string
+		wantErr bool
+	}{
+		{name: "route wildcard", dnsName: "*." + constants.RouteDomain},
+		{name: "route domain", dnsName: constants.RouteDomain},
+		{name: "host name", dnsName: constants.HostName},
+		{name: "other domain", dnsName: "www.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certPEM, _, err := ca.Issue([]string{tt.dnsName})
+			if err != nil {
+				t.Fatal(err)
+			}
+			block, _ := pem.Decode(certPEM)
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			host := tt.dnsName
+			if host[0] == '*' {
+				host = "console" + host[1:]
+			}
+			_, err = cert.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify(%s): got %v, want error %v", host, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package certs
+
+import (
+	"archive/tar"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExpiringWithin is how close to its expiry a certificate is reported as
+// expiring
+const ExpiringWithin = 30 * 24 * time.Hour
+
+// Certificate states
+const (
+	StatusValid    = "valid"
+	StatusExpiring = "expiring"
+	StatusExpired  = "expired"
+)
+
+// Certificate is the expiry of a certificate file, of the certificate that
+// expires first for files holding several
+type Certificate struct {
+	// Source is the file or kubeconfig field holding the certificate
+	Source   string    `json:"source"`
+	Subject  string    `json:"subject"`
+	NotAfter time.Time `json:"notAfter"`
+	Status   string    `json:"status"`
+	// CA is set for CA certificates, which sign the others
+	CA bool `json:"ca,omitempty"`
+}
+
+// Inspect returns the certificate of data, PEM or DER encoded, that expires first
+func Inspect(source string, data []byte, now time.Time) (*Certificate, error) {
+	var certs []*x509.Certificate
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing a certificate of %s: %w", source, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		cert, err := x509.ParseCertificate(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s holds no certificate", source)
+		}
+		certs = append(certs, cert)
+	}
+	first := certs[0]
+	for _, c := range certs[1:] {
+		if c.NotAfter.Before(first.NotAfter) {
+			first = c
+		}
+	}
+	return &Certificate{
+		Source:   source,
+		Subject:  first.Subject.CommonName,
+		NotAfter: first.NotAfter,
+		Status:   status(first.NotAfter, now),
+		CA:       first.IsCA,
+	}, nil
+}
+
+func status(notAfter, now time.Time) string {
+	switch {
+	case now.After(notAfter):
+		return StatusExpired
+	case now.Add(ExpiringWithin).After(notAfter):
+		return StatusExpiring
+	default:
+		return StatusValid
+	}
+}
+
+// InspectTar returns the certificates of the .crt files of the tar archive,
+// their sources are prefix joined with the archive paths
+func InspectTar(r io.Reader, prefix string, now time.Time) ([]Certificate, error) {
+	var certs []Certificate
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading the certificates archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(hdr.Name, ".crt") {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := Inspect(path.Join(prefix, hdr.Name), data, now)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, *cert)
+	}
+	return certs, nil
+}
+
+// Sort orders certificates by expiry, the first to expire first
+func Sort(certs []Certificate) {
+	sort.SliceStable(certs, func(i, j int) bool {
+		return certs[i].NotAfter.Before(certs[j].NotAfter)
+	})
+}
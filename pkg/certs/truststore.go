@@ -0,0 +1,102 @@
+package certs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/minc-org/minc/pkg/exec"
+	"github.com/minc-org/minc/pkg/log"
+)
+
+// systemStore is a Linux system trust store: the directory of its anchors and
+// the command rebuilding the store from them
+type systemStore struct {
+	dir    string
+	file   string
+	update []string
+}
+
+// linuxStores are tried in order, the first whose directory exists is used
+var linuxStores = []systemStore{
+	// Fedora, RHEL, CentOS
+	{"/etc/pki/ca-trust/source/anchors", "minc-ca.pem", []string{"update-ca-trust", "extract"}},
+	// Debian, Ubuntu
+	{"/usr/local/share/ca-certificates", "minc-ca.crt", []string{"update-ca-certificates"}},
+	// openSUSE
+	{"/etc/pki/trust/anchors", "minc-ca.pem", []string{"update-ca-certificates"}},
+	// Arch
+	{"/etc/ca-certificates/trust-source/anchors", "minc-ca.crt", []string{"trust", "extract-compat"}},
+}
+
+// Trust adds the CA certificate to the host's system trust store
+func Trust(ctx context.Context, ca *CA) error {
+	switch runtime.GOOS {
+	case "linux":
+		s, err := linuxStore()
+		if err != nil {
+			return err
+		}
+		if err := run(ctx, "install", "-m", "0644", ca.CertPath, filepath.Join(s.dir, s.file)); err != nil {
+			return err
+		}
+		return run(ctx, s.update...)
+	case "darwin":
+		return run(ctx, "security", "add-trusted-cert", "-d", "-r", "trustRoot",
+			"-k", "/Library/Keychains/System.keychain", ca.CertPath)
+	case "windows":
+		return run(ctx, "certutil", "-addstore", "-f", "ROOT", ca.CertPath)
+	default:
+		return fmt.Errorf("adding the CA to the trust store is not supported on %s, trust %s manually", runtime.GOOS, ca.CertPath)
+	}
+}
+
+// Untrust removes the CA certificate from the host's system trust store
+func Untrust(ctx context.Context, ca *CA) error {
+	switch runtime.GOOS {
+	case "linux":
+		s, err := linuxStore()
+		if err != nil {
+			return err
+		}
+		if err := run(ctx, "rm", "-f", filepath.Join(s.dir, s.file)); err != nil {
+			return err
+		}
+		return run(ctx, s.update...)
+	case "darwin":
+		return run(ctx, "security", "remove-trusted-cert", "-d", ca.CertPath)
+	case "windows":
+		return run(ctx, "certutil", "-delstore", "ROOT", ca.Cert.SerialNumber.Text(16))
+	default:
+		return fmt.Errorf("removing the CA from the trust store is not supported on %s", runtime.GOOS)
+	}
+}
+
+func linuxStore() (*systemStore, error) {
+	for _, s := range linuxStores {
+		if info, err := os.Stat(s.dir); err == nil && info.IsDir() {
+			return &s, nil
+		}
+	}
+	return nil, fmt.Errorf("no supported system trust store found, add the CA manually")
+}
+
+// run runs a trust store command, with sudo when not root like the podman
+// provider does
+func run(ctx context.Context, argv ...string) error {
+	var cmd exec.Cmd
+	if runtime.GOOS != "windows" && os.Geteuid() != 0 {
+		log.Debug("Running with sudo:", "command", strings.Join(argv, " "))
+		cmd = exec.CommandContext(ctx, "sudo", argv...)
+	} else {
+		cmd = exec.CommandContext(ctx, argv[0], argv[1:]...)
+	}
+	out, err := exec.CombinedOutputLines(cmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", strings.Join(argv, " "), err, strings.Join(out, "\n"))
+	}
+	return nil
+}
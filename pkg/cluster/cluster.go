@@ -10,24 +10,46 @@ import (
 	"time"
 )
 
-func GetPodStatus(kubeConfig []byte) error {
-	// Create Kubernetes client
+// podStatusBackoff polls pods with a linear backoff, about 20s in total
+var podStatusBackoff = retry.Backoff{
+	Strategy:     retry.Linear,
+	InitialDelay: 2 * time.Second,
+	MaxAttempts:  5,
+}
+
+func newClientSet(kubeConfig []byte) (*kubernetes.Clientset, error) {
 	config, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
 	if err != nil {
-		return fmt.Errorf("failed to build config from kubeconfig bytes: %v", err)
+		return nil, fmt.Errorf("failed to build config from kubeconfig bytes: %v", err)
 	}
 
 	clientSet, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return fmt.Errorf("failed to create Kubernetes client: %v", err)
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	return clientSet, nil
+}
+
+// GetPodStatus waits for the pods of the MicroShift namespaces to run
+func GetPodStatus(ctx context.Context, kubeConfig []byte) error {
+	return WaitForPods(ctx, kubeConfig, podStatusBackoff)
+}
+
+// WaitForPods polls the pods of the MicroShift namespaces with backoff until
+// all of them run
+func WaitForPods(ctx context.Context, kubeConfig []byte, backoff retry.Backoff) error {
+	// Create Kubernetes client
+	clientSet, err := newClientSet(kubeConfig)
+	if err != nil {
+		return err
 	}
 
 	// Define namespaces to check
 	namespaces := []string{"kube-flannel", "kube-proxy", "kube-system", "openshift-dns", "openshift-ingress", "openshift-service-ca"}
 
-	for _, ns := range namespaces {
-		podStatusFunc := func() error {
-			pods, err := clientSet.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{})
+	podStatusFunc := func(ctx context.Context) error {
+		for _, ns := range namespaces {
+			pods, err := clientSet.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
 			if err != nil {
 				return fmt.Errorf("failed to get pods in namespace %s: %v", ns, err)
 			}
@@ -36,9 +58,8 @@ func GetPodStatus(kubeConfig []byte) error {
 					return fmt.Errorf("pod %s in namespace %s is not running. Current status: %s", pod.Name, ns, pod.Status.Phase)
 				}
 			}
-			return nil
 		}
-		return retry.Retry(podStatusFunc, 5, 2*time.Second)
+		return nil
 	}
-	return nil
+	return retry.Do(ctx, backoff, podStatusFunc)
 }
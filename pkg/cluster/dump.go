@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Dump returns the nodes, pods and events of all namespaces as indented JSON,
+// keyed by file name.
+func Dump(ctx context.Context, kubeConfig []byte) (map[string][]byte, error) {
+	clientSet, err := newClientSet(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	dump := map[string][]byte{}
+	nodes, err := clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	if dump["nodes.json"], err = json.MarshalIndent(nodes, "", "  "); err != nil {
+		return nil, err
+	}
+
+	pods, err := clientSet.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	if dump["pods.json"], err = json.MarshalIndent(pods, "", "  "); err != nil {
+		return nil, err
+	}
+
+	events, err := clientSet.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %v", err)
+	}
+	if dump["events.json"], err = json.MarshalIndent(events, "", "  "); err != nil {
+		return nil, err
+	}
+	return dump, nil
+}
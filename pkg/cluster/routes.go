@@ -0,0 +1,146 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/retry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// routeResource is the OpenShift Route API served by MicroShift
+var routeResource = schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}
+
+// rewatchDelay is the pause before watching the routes again after the watch ended
+const rewatchDelay = 2 * time.Second
+
+// relistBackoff retries listing and watching the routes, e.g. while MicroShift
+// restarts, until the context is done
+var relistBackoff = retry.Backoff{
+	Strategy:     retry.Exponential,
+	InitialDelay: rewatchDelay,
+	MaxDelay:     30 * time.Second,
+	Jitter:       0.2,
+	OnAttempt: func(a retry.Attempt) {
+		log.Warn("Watching the routes failed, retrying", "err", a.Err, "retryIn", a.NextDelay.Round(time.Second))
+	},
+}
+
+// Route is an OpenShift route exposed by the MicroShift router
+type Route struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	Path      string `json:"path,omitempty"`
+	// TLS is the route's TLS termination (edge, passthrough or reencrypt),
+	// empty for plain http routes
+	TLS     string `json:"tls,omitempty"`
+	Service string `json:"service"`
+}
+
+// newDynamicClient connects to the API server on 127.0.0.1 while verifying its
+// certificate for constants.HostName, so it works when the host can not
+// resolve HostName, e.g. offline
+func newDynamicClient(kubeConfig []byte) (dynamic.Interface, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build config from kubeconfig bytes: %v", err)
+	}
+	config.Host = "https://127.0.0.1:6443"
+	config.TLSClientConfig.ServerName = constants.HostName
+	return dynamic.NewForConfig(config)
+}
+
+// ListRoutes returns the routes of the namespace, of all namespaces when
+// namespace is empty, sorted by namespace and name
+func ListRoutes(ctx context.Context, kubeConfig []byte, namespace string) ([]Route, error) {
+	client, err := newDynamicClient(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	routes, _, err := listRoutes(ctx, client, namespace)
+	return routes, err
+}
+
+func listRoutes(ctx context.Context, client dynamic.Interface, namespace string) ([]Route, string, error) {
+	list, err := client.Resource(routeResource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list routes: %v", err)
+	}
+	routes := make([]Route, 0, len(list.Items))
+	for i := range list.Items {
+		routes = append(routes, toRoute(&list.Items[i]))
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Namespace != routes[j].Namespace {
+			return routes[i].Namespace < routes[j].Namespace
+		}
+		return routes[i].Name < routes[j].Name
+	})
+	return routes, list.GetResourceVersion(), nil
+}
+
+func toRoute(u *unstructured.Unstructured) Route {
+	r := Route{Namespace: u.GetNamespace(), Name: u.GetName()}
+	r.Host, _, _ = unstructured.NestedString(u.Object, "spec", "host")
+	r.Path, _, _ = unstructured.NestedString(u.Object, "spec", "path")
+	r.TLS, _, _ = unstructured.NestedString(u.Object, "spec", "tls", "termination")
+	r.Service, _, _ = unstructured.NestedString(u.Object, "spec", "to", "name")
+	return r
+}
+
+// WatchRoutes calls fn with all the routes of all namespaces, then again on
+// every change, until ctx is done. The watch is restarted when the API server
+// closes it, and listing the routes again is retried with relistBackoff.
+// Errors of fn end the watch.
+func WatchRoutes(ctx context.Context, kubeConfig []byte, fn func([]Route) error) error {
+	client, err := newDynamicClient(kubeConfig)
+	if err != nil {
+		return err
+	}
+	return watchRoutes(ctx, client, relistBackoff, fn)
+}
+
+func watchRoutes(ctx context.Context, client dynamic.Interface, backoff retry.Backoff, fn func([]Route) error) error {
+	for {
+		var w watch.Interface
+		err := retry.Do(ctx, backoff, func(ctx context.Context) error {
+			routes, version, err := listRoutes(ctx, client, "")
+			if err != nil {
+				return err
+			}
+			if err := fn(routes); err != nil {
+				return retry.Permanent(err)
+			}
+			w, err = client.Resource(routeResource).Watch(ctx, metav1.ListOptions{ResourceVersion: version})
+			if err != nil {
+				return fmt.Errorf("failed to watch routes: %v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// any event changes the route set, the full list is simpler than
+		// applying the events one by one
+		select {
+		case <-ctx.Done():
+		case <-w.ResultChan():
+		}
+		w.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(rewatchDelay):
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/minc-org/minc/pkg/retry"
+)
+
+func route(namespace, name, host string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "route.openshift.io/v1",
+		"kind":       "Route",
+		"metadata":   map[string]interface{}{"namespace": namespace, "name": name},
+		"spec": map[string]interface{}{
+			"host": host,
+			"to":   map[string]interface{}{"name": name},
+		},
+	}}
+}
+
+func TestWatchRoutes(t *testing.T) {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{routeResource: "RouteList"},
+		route("default", "web", "web.apps.127.0.0.1.nip.io"))
+	// the first lists fail like while MicroShift restarts
+	failures := 2
+	client.PrependReactor("list", "routes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			failures--
+			return true, nil, errors.New("connection refused")
+		}
+		return false, nil, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var got []Route
+	backoff := retry.Backoff{Strategy: retry.Constant, InitialDelay: time.Millisecond}
+	err := watchRoutes(ctx, client, backoff, func(routes []Route) error {
+		got = routes
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	want := []Route{{Namespace: "default", Name: "web", Host: "web.apps.127.0.0.1.nip.io", Service: "web"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got routes %+v, want %+v", got, want)
+	}
+	if failures != 0 {
+		t.Errorf("%d failing lists left", failures)
+	}
+}
+
+func TestWatchRoutesCallbackError(t *testing.T) {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{routeResource: "RouteList"})
+	want := errors.New("write failed")
+	calls := 0
+	backoff := retry.Backoff{Strategy: retry.Constant, InitialDelay: time.Millisecond}
+	err := watchRoutes(context.Background(), client, backoff, func([]Route) error {
+		calls++
+		return want
+	})
+	if !errors.Is(err, want) || calls != 1 {
+		t.Errorf("got %v after %d calls, want %v after 1", err, calls, want)
+	}
+}
@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApplyTLSSecret creates or replaces the kubernetes.io/tls secret
+func ApplyTLSSecret(ctx context.Context, kubeConfig []byte, namespace, name string, cert, key []byte) error {
+	clientSet, err := newClientSet(kubeConfig)
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       cert,
+			corev1.TLSPrivateKeyKey: key,
+		},
+	}
+	secrets := clientSet.CoreV1().Secrets(namespace)
+	_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply secret %s/%s: %v", namespace, name, err)
+	}
+	return nil
+}
+
+// DeleteSecret deletes the secret, a missing one is not an error
+func DeleteSecret(ctx context.Context, kubeConfig []byte, namespace, name string) error {
+	clientSet, err := newClientSet(kubeConfig)
+	if err != nil {
+		return err
+	}
+	err = clientSet.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete secret %s/%s: %v", namespace, name, err)
+	}
+	return nil
+}
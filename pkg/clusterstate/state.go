@@ -0,0 +1,82 @@
+// Package clusterstate records how the minc cluster was created, so later
+// commands such as snapshot can recreate it the same way.
+package clusterstate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/signature"
+)
+
+const stateFile = "cluster.json"
+
+// State is the recorded state of the cluster
+type State struct {
+	Created time.Time         `json:"created"`
+	Cluster *types.CreateType `json:"cluster"`
+	// Signature is the signature verification of the cluster's image
+	Signature *signature.Result `json:"signature,omitempty"`
+	// NetworkCreated is set when minc created the cluster's network, an
+	// existing network it reused is kept on delete
+	NetworkCreated bool `json:"networkCreated,omitempty"`
+}
+
+// Path returns the path to the state file, or an error if the user config dir cannot be resolved.
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "minc", stateFile), nil
+}
+
+// Load reads the recorded state, the error matches os.ErrNotExist when no
+// cluster was recorded.
+func Load() (*State, error) {
+	p, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Save records s after a successful create.
+func Save(s *State) error {
+	p, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0644)
+}
+
+// Remove deletes the recorded state; call after a successful minc delete.
+func Remove() error {
+	p, err := Path()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -3,6 +3,8 @@ package constants
 import (
 	"fmt"
 	"runtime"
+
+	"github.com/minc-org/minc/pkg/reference"
 )
 
 const (
@@ -13,6 +15,21 @@ const (
 	Registry      = "quay.io"
 	RegistryOrg   = "minc-org"
 	ImageName     = "minc"
+	// RouteDomain is the domain routes are served on, as <name>-<namespace>.RouteDomain
+	RouteDomain = "apps." + HostName
+	// StorageVolume is the named volume used for container storage when the overlay cache is disabled
+	StorageVolume = "minc-container-storage"
+	// DataVolume is the named volume holding /var/lib/microshift with persistent data
+	DataVolume = "minc-microshift-data"
+	// DefaultClusterCIDR and DefaultServiceCIDR are MicroShift's default pod
+	// and service networks
+	DefaultClusterCIDR = "10.42.0.0/16"
+	DefaultServiceCIDR = "10.43.0.0/16"
+	// NetworkName is the network of the cluster, other containers join it with
+	// 'minc network connect'
+	NetworkName = "minc"
+	// UShiftDataDir is MicroShift's data directory: etcd, certificates and kubeconfigs
+	UShiftDataDir = "/var/lib/microshift"
 )
 
 var (
@@ -23,7 +40,13 @@ func GetImageRegistry() string {
 	return fmt.Sprintf("%s/%s/%s", Registry, RegistryOrg, ImageName)
 }
 
+// GetUShiftImage returns the MicroShift image of version for this
+// architecture in the image repository, or the default one. An image with a
+// tag or digest is used as is, the version is ignored then.
 func GetUShiftImage(image, version string) string {
+	if ref, err := reference.Parse(image); err == nil && ref.Pinned() {
+		return image
+	}
 	if image != "" {
 		return fmt.Sprintf("%s:%s-%s", image, version, runtime.GOARCH)
 	}
@@ -0,0 +1,90 @@
+package diagnose
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minc-org/minc/pkg/redact"
+)
+
+// Bundle is a gzipped tarball collecting diagnostic files. Every file is
+// redacted before it is written, and collection failures are recorded in
+// errors.txt instead of aborting the bundle.
+type Bundle struct {
+	file   *os.File
+	gz     *gzip.Writer
+	tw     *tar.Writer
+	prefix string
+	errs   []string
+}
+
+// New creates the bundle at path, entries are stored below a directory named
+// after the file so the tarball extracts cleanly.
+func New(path string) (*Bundle, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(f)
+	return &Bundle{
+		file:   f,
+		gz:     gz,
+		tw:     tar.NewWriter(gz),
+		prefix: strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".tar"),
+	}, nil
+}
+
+// Add redacts data and writes it to the bundle as name.
+func (b *Bundle) Add(name string, data []byte) error {
+	data = redact.Redact(data)
+	hdr := &tar.Header{
+		Name:    b.prefix + "/" + name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := b.tw.Write(data)
+	return err
+}
+
+// Collect adds the output of fn as name, or records the error if fn fails.
+// Partial output is kept since it often explains the failure.
+func (b *Bundle) Collect(name string, fn func() ([]byte, error)) error {
+	data, err := fn()
+	if err != nil {
+		b.RecordError(name, err)
+		if len(data) == 0 {
+			return nil
+		}
+	}
+	return b.Add(name, data)
+}
+
+// RecordError notes in errors.txt that collecting name failed.
+func (b *Bundle) RecordError(name string, err error) {
+	b.errs = append(b.errs, fmt.Sprintf("%s: %v", name, err))
+}
+
+// Close writes errors.txt and flushes the bundle to disk. The file is closed
+// even when writing fails.
+func (b *Bundle) Close() error {
+	var err error
+	if len(b.errs) > 0 {
+		err = b.Add("errors.txt", []byte(strings.Join(b.errs, "\n")+"\n"))
+	}
+	for _, c := range []io.Closer{b.tw, b.gz, b.file} {
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Result is the canned outcome of a command run by FakeCmder or ReplayCmder
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	// Err is returned for failures without an exit code, e.g. a missing executable
+	Err error
+}
+
+// ExitError reports the non zero exit code of a canned command, like
+// os/exec.ExitError does for real ones
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the exit code, matching os/exec.ExitError
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// cannedCmd implements Cmd by looking up its Result instead of running anything
+type cannedCmd struct {
+	argv   []string
+	lookup func(argv []string) (*Result, error)
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var _ Cmd = &cannedCmd{}
+
+func (c *cannedCmd) SetEnv(...string) Cmd {
+	return c
+}
+
+func (c *cannedCmd) SetStdin(io.Reader) Cmd {
+	return c
+}
+
+func (c *cannedCmd) SetStdout(w io.Writer) Cmd {
+	c.stdout = w
+	return c
+}
+
+func (c *cannedCmd) SetStderr(w io.Writer) Cmd {
+	c.stderr = w
+	return c
+}
+
+// Run writes the canned output and returns a *RunError for failures
+func (c *cannedCmd) Run() error {
+	res, err := c.lookup(c.argv)
+	if err != nil {
+		return &RunError{Command: c.argv, Inner: err}
+	}
+	if c.stdout != nil {
+		if _, err := c.stdout.Write(res.Stdout); err != nil {
+			return err
+		}
+	}
+	if c.stderr != nil {
+		if _, err := c.stderr.Write(res.Stderr); err != nil {
+			return err
+		}
+	}
+	inner := res.Err
+	if inner == nil && res.ExitCode != 0 {
+		inner = &ExitError{Code: res.ExitCode}
+	}
+	if inner == nil {
+		return nil
+	}
+	return &RunError{
+		Command: c.argv,
+		Output:  append(append([]byte{}, res.Stdout...), res.Stderr...),
+		Stderr:  res.Stderr,
+		Inner:   inner,
+	}
+}
+
+// cannedCmder builds cannedCmds for a lookup function
+type cannedCmder struct {
+	lookup func(argv []string) (*Result, error)
+}
+
+func (c *cannedCmder) Command(name string, arg ...string) Cmd {
+	return &cannedCmd{
+		argv:   append([]string{name}, arg...),
+		lookup: c.lookup,
+	}
+}
+
+// CommandContext ignores ctx, canned commands return immediately
+func (c *cannedCmder) CommandContext(_ context.Context, name string, arg ...string) Cmd {
+	return c.Command(name, arg...)
+}
+
+func argvString(argv []string) string {
+	return strings.Join(argv, " ")
+}
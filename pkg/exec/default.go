@@ -4,8 +4,9 @@ import "context"
 
 // DefaultCmder is a LocalCmder instance used for convenience, packages
 // originally using os/exec.Command can instead use pkg/exec/exec.Command
-// which forwards to this instance
-var DefaultCmder = &LocalCmder{}
+// which forwards to this instance. register swaps it for the runner of
+// MINC_EXEC_RECORD and MINC_EXEC_REPLAY.
+var DefaultCmder Cmder = &LocalCmder{}
 
 // Command is a convenience wrapper over DefaultCmder.Command
 func Command(command string, args ...string) Cmd {
@@ -0,0 +1,101 @@
+package exec
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Wildcard matches any single argument in an expected argv
+const Wildcard = "*"
+
+// FakeCmder is a scripted Cmder: commands are matched against the expected
+// argv in the order the expectations were added and return canned output.
+// Unexpected commands fail with a *RunError.
+type FakeCmder struct {
+	cannedCmder
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        [][]string
+}
+
+var _ Cmder = &FakeCmder{}
+
+// Expectation is a scripted command, see FakeCmder.Expect
+type Expectation struct {
+	argv   []string
+	result Result
+	once   bool
+	used   bool
+}
+
+func NewFakeCmder() *FakeCmder {
+	f := &FakeCmder{}
+	f.lookup = f.match
+	return f
+}
+
+// Expect adds an expectation for argv, use Wildcard for arguments that vary.
+// By default it succeeds without output and matches any number of times.
+func (f *FakeCmder) Expect(argv ...string) *Expectation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e := &Expectation{argv: argv}
+	f.expectations = append(f.expectations, e)
+	return e
+}
+
+// Calls returns the argv of every command run so far
+func (f *FakeCmder) Calls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string{}, f.calls...)
+}
+
+func (f *FakeCmder) match(argv []string) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, argv)
+	for _, e := range f.expectations {
+		if e.once && e.used {
+			continue
+		}
+		if argvMatches(e.argv, argv) {
+			e.used = true
+			res := e.result
+			return &res, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected command: %s", argvString(argv))
+}
+
+// Return sets the command's stdout
+func (e *Expectation) Return(stdout string) *Expectation {
+	e.result.Stdout = []byte(stdout)
+	return e
+}
+
+// Fail makes the command exit with exitCode and print stderr
+func (e *Expectation) Fail(exitCode int, stderr string) *Expectation {
+	e.result.ExitCode = exitCode
+	e.result.Stderr = []byte(stderr)
+	return e
+}
+
+// Once makes the expectation match a single command only, so later
+// expectations for the same argv can script a sequence of results
+func (e *Expectation) Once() *Expectation {
+	e.once = true
+	return e
+}
+
+func argvMatches(expected, argv []string) bool {
+	if len(expected) != len(argv) {
+		return false
+	}
+	for i := range expected {
+		if expected[i] != Wildcard && expected[i] != argv[i] {
+			return false
+		}
+	}
+	return true
+}
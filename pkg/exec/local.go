@@ -1,7 +1,6 @@
 package exec
 
 import (
-	"bytes"
 	"context"
 	"github.com/minc-org/minc/pkg/log"
 	"io"
@@ -69,8 +68,11 @@ func (cmd *LocalCmd) Run() error {
 	//
 	// Given this, we must synchronize capturing the output to a buffer
 	// IFF ! interfaceEqual(cmd.Sterr, cmd.Stdout)
-	var combinedOutput bytes.Buffer
-	var combinedOutputWriter io.Writer = &combinedOutput
+	// only the tail of the output is kept for the error, commands streaming
+	// large outputs such as volume archives must not be held in memory
+	combinedOutput := &tailBuffer{max: maxCapturedOutput}
+	stderr := &tailBuffer{max: maxCapturedOutput}
+	var combinedOutputWriter io.Writer = combinedOutput
 	if cmd.Stdout == nil && cmd.Stderr == nil {
 		// Case 1: If stdout and stderr are nil, we can just use the buffer
 		// The buffer will be == and Go will use one fd / goroutine
@@ -88,24 +90,30 @@ func (cmd *LocalCmd) Run() error {
 		// combined output writer.
 		// Go will use different fds / write routines for stdout and stderr
 		combinedOutputWriter = &mutexWriter{
-			writer: &combinedOutput,
+			writer: combinedOutput,
 		}
-		// wrap writers if non-nil
+		// wrap writers if non-nil, stderr is also captured on its own
+		// since only the stderr goroutine writes to that buffer
 		if cmd.Stdout != nil {
 			cmd.Stdout = io.MultiWriter(cmd.Stdout, combinedOutputWriter)
 		} else {
 			cmd.Stdout = combinedOutputWriter
 		}
 		if cmd.Stderr != nil {
-			cmd.Stderr = io.MultiWriter(cmd.Stderr, combinedOutputWriter)
+			cmd.Stderr = io.MultiWriter(cmd.Stderr, combinedOutputWriter, stderr)
 		} else {
-			cmd.Stderr = combinedOutputWriter
+			cmd.Stderr = io.MultiWriter(combinedOutputWriter, stderr)
 		}
 	}
 
 	if err := cmd.Cmd.Run(); err != nil {
-		log.Debug(combinedOutput.String(), "Args", cmd.Args)
-		return err
+		log.Debug(string(combinedOutput.Bytes()), "Args", cmd.Args)
+		return &RunError{
+			Command: cmd.Args,
+			Output:  combinedOutput.Bytes(),
+			Stderr:  stderr.Bytes(),
+			Inner:   err,
+		}
 	}
 	return nil
 }
@@ -135,3 +143,24 @@ func (m *mutexWriter) Write(b []byte) (int, error) {
 	n, err := m.writer.Write(b)
 	return n, err
 }
+
+// maxCapturedOutput bounds the output kept for RunError
+const maxCapturedOutput = 64 * 1024
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	buf []byte
+	max int
+}
+
+func (t *tailBuffer) Write(b []byte) (int, error) {
+	t.buf = append(t.buf, b...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(b), nil
+}
+
+func (t *tailBuffer) Bytes() []byte {
+	return t.buf
+}
@@ -0,0 +1,269 @@
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/minc-org/minc/pkg/redact"
+)
+
+const (
+	// RecordEnv names a file every command run is appended to as a transcript
+	RecordEnv = "MINC_EXEC_RECORD"
+	// ReplayEnv names a transcript to replay instead of running commands
+	ReplayEnv = "MINC_EXEC_REPLAY"
+
+	// maxRecordedStdout bounds the stdout kept in a transcript, larger outputs
+	// such as exported volumes are only recorded by size and hash
+	maxRecordedStdout = 1024 * 1024
+)
+
+// Record is a single command of a transcript, stored as one JSON object per line
+type Record struct {
+	Argv   []string `json:"argv"`
+	Stdout string   `json:"stdout,omitempty"`
+	// StdoutSize and StdoutSHA256 replace Stdout when it exceeds
+	// maxRecordedStdout, such stdout replays empty
+	StdoutSize   int64  `json:"stdoutSize,omitempty"`
+	StdoutSHA256 string `json:"stdoutSHA256,omitempty"`
+	Stderr       string `json:"stderr,omitempty"`
+	ExitCode     int    `json:"exitCode,omitempty"`
+	// Error is set for failures without an exit code, e.g. a missing executable
+	Error string `json:"error,omitempty"`
+}
+
+// CmderFromEnv returns a ReplayCmder or RecordingCmder when MINC_EXEC_REPLAY
+// or MINC_EXEC_RECORD are set, and DefaultCmder otherwise
+func CmderFromEnv() (Cmder, error) {
+	if path := os.Getenv(ReplayEnv); path != "" {
+		return NewReplayCmder(path)
+	}
+	if path := os.Getenv(RecordEnv); path != "" {
+		return NewRecordingCmder(DefaultCmder, path)
+	}
+	return DefaultCmder, nil
+}
+
+// RecordingCmder runs commands with an inner Cmder and appends each of them
+// with its output and exit code to a transcript file. Secrets in the
+// arguments and output are redacted, see redact.Redact. Records are written
+// unbuffered, Close only releases the file.
+type RecordingCmder struct {
+	inner Cmder
+	mu    sync.Mutex
+	file  *os.File
+}
+
+var _ Cmder = &RecordingCmder{}
+
+func NewRecordingCmder(inner Cmder, path string) (*RecordingCmder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening exec transcript: %w", err)
+	}
+	return &RecordingCmder{inner: inner, file: f}, nil
+}
+
+// Close closes the transcript file, commands run afterwards fail to record
+func (r *RecordingCmder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+func (r *RecordingCmder) Command(name string, arg ...string) Cmd {
+	return &recordingCmd{
+		Cmd:      r.inner.Command(name, arg...),
+		argv:     append([]string{name}, arg...),
+		recorder: r,
+	}
+}
+
+func (r *RecordingCmder) CommandContext(ctx context.Context, name string, arg ...string) Cmd {
+	return &recordingCmd{
+		Cmd:      r.inner.CommandContext(ctx, name, arg...),
+		argv:     append([]string{name}, arg...),
+		recorder: r,
+	}
+}
+
+func (r *RecordingCmder) write(rec *Record) error {
+	rec.Argv = redact.Strings(rec.Argv)
+	rec.Stdout = string(redact.Redact([]byte(rec.Stdout)))
+	rec.Stderr = string(redact.Redact([]byte(rec.Stderr)))
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.file.Write(append(data, '\n'))
+	return err
+}
+
+// recordingCmd tees the output of the wrapped Cmd into the transcript
+type recordingCmd struct {
+	Cmd
+	argv     []string
+	recorder *RecordingCmder
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+func (c *recordingCmd) SetStdout(w io.Writer) Cmd {
+	c.stdout = w
+	return c
+}
+
+func (c *recordingCmd) SetStderr(w io.Writer) Cmd {
+	c.stderr = w
+	return c
+}
+
+func (c *recordingCmd) SetEnv(env ...string) Cmd {
+	c.Cmd.SetEnv(env...)
+	return c
+}
+
+func (c *recordingCmd) SetStdin(r io.Reader) Cmd {
+	c.Cmd.SetStdin(r)
+	return c
+}
+
+func (c *recordingCmd) Run() error {
+	stdout := &headBuffer{max: maxRecordedStdout, hash: sha256.New()}
+	stderr := &tailBuffer{max: maxCapturedOutput}
+	stdoutW, stderrW := c.stdout, c.stderr
+	// stdout and stderr are captured separately, so a shared writer now sees
+	// writes from two goroutines
+	if stdoutW != nil && interfaceEqual(stdoutW, stderrW) {
+		stdoutW = &mutexWriter{writer: stdoutW}
+		stderrW = stdoutW
+	}
+	c.Cmd.SetStdout(teeWriter(stdoutW, stdout))
+	c.Cmd.SetStderr(teeWriter(stderrW, stderr))
+	err := c.Cmd.Run()
+
+	rec := &Record{
+		Argv:   c.argv,
+		Stderr: string(stderr.Bytes()),
+	}
+	if stdout.size > int64(stdout.max) {
+		rec.StdoutSize = stdout.size
+		rec.StdoutSHA256 = hex.EncodeToString(stdout.hash.Sum(nil))
+	} else {
+		rec.Stdout = string(stdout.buf)
+	}
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		rec.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		rec.Error = err.Error()
+	}
+	if werr := c.recorder.write(rec); werr != nil {
+		return errors.Join(err, fmt.Errorf("recording command: %w", werr))
+	}
+	return err
+}
+
+func teeWriter(w io.Writer, buf io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(w, buf)
+}
+
+// headBuffer keeps what is written to it up to max bytes and drops it all
+// once more is written, the size and hash cover the whole output
+type headBuffer struct {
+	buf  []byte
+	max  int
+	size int64
+	hash hash.Hash
+}
+
+func (h *headBuffer) Write(b []byte) (int, error) {
+	h.size += int64(len(b))
+	h.hash.Write(b)
+	if h.size > int64(h.max) {
+		h.buf = nil
+	} else {
+		h.buf = append(h.buf, b...)
+	}
+	return len(b), nil
+}
+
+// ReplayCmder serves commands from a transcript written by RecordingCmder.
+// Each argv replays its recorded results in order, the last one repeats
+// once they are used up so polling loops can replay as well. Commands are
+// matched on their redacted argv, as recorded, so a command with a secret
+// argument replays whatever the secret is.
+type ReplayCmder struct {
+	cannedCmder
+	mu      sync.Mutex
+	records map[string][]*Record
+}
+
+var _ Cmder = &ReplayCmder{}
+
+func NewReplayCmder(path string) (*ReplayCmder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening exec transcript: %w", err)
+	}
+	defer f.Close()
+
+	r := &ReplayCmder{records: map[string][]*Record{}}
+	r.lookup = r.replay
+	scanner := bufio.NewScanner(f)
+	// recorded outputs such as journals can be long
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		rec := &Record{}
+		if err := json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			return nil, fmt.Errorf("exec transcript %s line %d: %w", path, line, err)
+		}
+		key := argvString(rec.Argv)
+		r.records[key] = append(r.records[key], rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *ReplayCmder) replay(argv []string) (*Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := argvString(redact.Strings(argv))
+	recs := r.records[key]
+	if len(recs) == 0 {
+		return nil, fmt.Errorf("command not found in transcript: %s", key)
+	}
+	rec := recs[0]
+	if len(recs) > 1 {
+		r.records[key] = recs[1:]
+	}
+	res := &Result{
+		Stdout:   []byte(rec.Stdout),
+		Stderr:   []byte(rec.Stderr),
+		ExitCode: rec.ExitCode,
+	}
+	if rec.Error != "" {
+		res.Err = errors.New(rec.Error)
+	}
+	return res, nil
+}
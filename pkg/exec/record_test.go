@@ -0,0 +1,126 @@
+package exec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordingCmder(t *testing.T) {
+	large := strings.Repeat("x", maxRecordedStdout+1)
+	largeSum := sha256.Sum256([]byte(large))
+	kubeConfig := "users:\n- name: user\n  user:\n    client-key-data: c2VjcmV0\n"
+	tests := []struct {
+		name       string
+		argv       []string
+		stdout     string
+		wantStdout string
+		wantSize   int64
+		wantSHA256 string
+	}{
+		{
+			name:       "output",
+			argv:       []string{"podman", "ps"},
+			stdout:     "microshift running\n",
+			wantStdout: "microshift running\n",
+		},
+		{
+			name:       "secrets",
+			argv:       []string{"podman", "exec", "microshift", "cat", "kubeconfig"},
+			stdout:     kubeConfig,
+			wantStdout: "users:\n- name: user\n  user:\n    client-key-data: <redacted>\n",
+		},
+		{
+			name:       "streamed",
+			argv:       []string{"podman", "volume", "export", "minc-data"},
+			stdout:     large,
+			wantSize:   int64(len(large)),
+			wantSHA256: hex.EncodeToString(largeSum[:]),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transcript := filepath.Join(t.TempDir(), "transcript.jsonl")
+			inner := NewFakeCmder()
+			inner.Expect(tt.argv...).Return(tt.stdout)
+			r, err := NewRecordingCmder(inner, transcript)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stdout bytes.Buffer
+			if err := r.Command(tt.argv[0], tt.argv[1:]...).SetStdout(&stdout).Run(); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("the caller got %d bytes of stdout, want %d", stdout.Len(), len(tt.stdout))
+			}
+			if err := r.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if err := r.Command(tt.argv[0], tt.argv[1:]...).Run(); err == nil {
+				t.Errorf("recording after Close succeeded")
+			}
+
+			replay, err := NewReplayCmder(transcript)
+			if err != nil {
+				t.Fatal(err)
+			}
+			recs := replay.records[argvString(tt.argv)]
+			if len(recs) != 1 {
+				t.Fatalf("got %d records, want 1", len(recs))
+			}
+			rec := recs[0]
+			if rec.Stdout != tt.wantStdout || rec.StdoutSize != tt.wantSize || rec.StdoutSHA256 != tt.wantSHA256 {
+				t.Errorf("recorded stdout %q, size %d, sha256 %q, want %q, %d, %q",
+					rec.Stdout, rec.StdoutSize, rec.StdoutSHA256, tt.wantStdout, tt.wantSize, tt.wantSHA256)
+			}
+			data, err := os.ReadFile(transcript)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(data, []byte("c2VjcmV0")) {
+				t.Errorf("the transcript holds a secret: %s", data)
+			}
+		})
+	}
+}
+
+func TestReplaySecretArgument(t *testing.T) {
+	transcript := filepath.Join(t.TempDir(), "transcript.jsonl")
+	inner := NewFakeCmder()
+	inner.Expect("podman", "login", "--password=s3cret", "quay.io").Return("Login Succeeded!\n")
+	r, err := NewRecordingCmder(inner, transcript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Command("podman", "login", "--password=s3cret", "quay.io").Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(transcript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("s3cret")) {
+		t.Errorf("the transcript holds the password: %s", data)
+	}
+
+	replay, err := NewReplayCmder(transcript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the argv is matched redacted, whatever the password of the replay
+	var stdout bytes.Buffer
+	if err := replay.Command("podman", "login", "--password=other", "quay.io").SetStdout(&stdout).Run(); err != nil {
+		t.Fatalf("replaying the login: %v", err)
+	}
+	if stdout.String() != "Login Succeeded!\n" {
+		t.Errorf("replayed %q", stdout.String())
+	}
+}
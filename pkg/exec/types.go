@@ -2,7 +2,9 @@ package exec
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"strings"
 )
 
 // Cmd abstracts over running a command somewhere, this is useful for testing
@@ -27,6 +29,36 @@ type Cmder interface {
 // RunError represents an error running a Cmd
 type RunError struct {
 	Command []string // [Name Args...]
-	Output  []byte   // Captured Stdout / Stderr of the command
+	Output  []byte   // Captured Stdout / Stderr of the command, its last 64KiB
+	Stderr  []byte   // Captured Stderr of the command, empty if it shared a writer with Stdout
 	Inner   error    // Underlying error if any
 }
+
+var _ error = &RunError{}
+
+// maxErrorLines bounds how much of the output RunError.Error includes,
+// the last lines of output usually carry the reason for the failure
+const maxErrorLines = 10
+
+// Error includes the command's stderr, or its combined output if stderr
+// was not captured separately, as it usually explains the failure
+func (e *RunError) Error() string {
+	msg := fmt.Sprintf("command %q failed with error: %v", strings.Join(e.Command, " "), e.Inner)
+	out := e.Stderr
+	if len(out) == 0 {
+		out = e.Output
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) > maxErrorLines {
+		lines = lines[len(lines)-maxErrorLines:]
+	}
+	if trimmed := strings.Join(lines, "\n"); trimmed != "" {
+		msg += ": " + trimmed
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error, e.g. an *os/exec.ExitError
+func (e *RunError) Unwrap() error {
+	return e.Inner
+}
@@ -0,0 +1,126 @@
+// Package hostdns resolves the cluster's host names without internet DNS:
+// through a managed block of the hosts file, or an embedded DNS server the
+// host's resolver forwards the cluster domain to.
+package hostdns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/minc-org/minc/pkg/constants"
+)
+
+// The managed block of the hosts file, everything between the markers is
+// rewritten by Update
+const (
+	beginMarker = "# BEGIN minc, managed by 'minc dns hosts'"
+	endMarker   = "# END minc"
+)
+
+// loopback is the address route hosts resolve to, the router ports are
+// published on the host
+const loopback = "127.0.0.1"
+
+// DefaultHostsFile returns the hosts file of the host
+func DefaultHostsFile() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("SystemRoot"), "System32", "drivers", "etc", "hosts")
+	}
+	return "/etc/hosts"
+}
+
+// Hosts returns the sorted, unique host names to resolve: the API server's
+// and the route hosts. Wildcard hosts can not be listed in a hosts file and
+// are left out.
+func Hosts(routeHosts []string) []string {
+	hosts := []string{constants.HostName}
+	for _, h := range routeHosts {
+		if h != "" && !strings.HasPrefix(h, "*") {
+			hosts = append(hosts, strings.ToLower(h))
+		}
+	}
+	slices.Sort(hosts)
+	return slices.Compact(hosts)
+}
+
+// Update replaces the managed block of the hosts file at path with entries
+// resolving hosts to 127.0.0.1. It reports whether the file changed, an
+// unchanged file is not written.
+func Update(path string, hosts []string) (bool, error) {
+	var block strings.Builder
+	block.WriteString(beginMarker + "\n")
+	for _, h := range hosts {
+		fmt.Fprintf(&block, "%s\t%s\n", loopback, h)
+	}
+	block.WriteString(endMarker + "\n")
+	return rewrite(path, block.String())
+}
+
+// Remove deletes the managed block from the hosts file at path
+func Remove(path string) error {
+	_, err := rewrite(path, "")
+	return err
+}
+
+// rewrite replaces the managed block of the file with block, appending it
+// when the file has none. The file is written in place, keeping its owner and
+// mode, and since /etc/hosts may be a bind mount in containers; the original
+// content is written back if the write fails.
+func rewrite(path, block string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	content := string(data)
+	eol := "\n"
+	if strings.Contains(content, "\r\n") {
+		eol = "\r\n"
+		block = strings.ReplaceAll(block, "\n", eol)
+	}
+	var updated string
+	begin := strings.Index(content, beginMarker)
+	end := strings.Index(content, endMarker)
+	switch {
+	case begin >= 0 && end > begin:
+		rest := content[end+len(endMarker):]
+		rest = strings.TrimPrefix(rest, eol)
+		updated = content[:begin] + block + rest
+	case begin >= 0 || end >= 0:
+		return false, fmt.Errorf("%s has an incomplete minc block, remove its lines between %q and %q", path, beginMarker, endMarker)
+	case block == "":
+		return false, nil
+	default:
+		if content != "" && !strings.HasSuffix(content, eol) {
+			content += eol
+		}
+		updated = content + block
+	}
+	if updated == string(data) {
+		return false, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return false, err
+	}
+	if _, err := f.WriteString(updated); err != nil {
+		if rbErr := restore(f, data); rbErr != nil {
+			err = fmt.Errorf("%w, restoring the original content of %s failed: %v", err, path, rbErr)
+		}
+		f.Close()
+		return false, err
+	}
+	return true, f.Close()
+}
+
+// restore writes data back to the start of the truncated file f
+func restore(f *os.File, data []byte) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.WriteAt(data, 0)
+	return err
+}
@@ -0,0 +1,94 @@
+package hostdns
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	const block = beginMarker + "\n127.0.0.1\tapi.127.0.0.1.nip.io\n" + endMarker + "\n"
+	const old = beginMarker + "\n127.0.0.1\told.127.0.0.1.nip.io\n" + endMarker + "\n"
+	tests := []struct {
+		name        string
+		content     string
+		block       string
+		want        string
+		wantChanged bool
+		wantErr     bool
+	}{
+		{
+			name:        "append",
+			content:     "127.0.0.1\tlocalhost",
+			block:       block,
+			want:        "127.0.0.1\tlocalhost\n" + block,
+			wantChanged: true,
+		},
+		{
+			name:        "append to an empty file",
+			block:       block,
+			want:        block,
+			wantChanged: true,
+		},
+		{
+			name:        "replace",
+			content:     "127.0.0.1\tlocalhost\n" + old + "::1\tlocalhost\n",
+			block:       block,
+			want:        "127.0.0.1\tlocalhost\n" + block + "::1\tlocalhost\n",
+			wantChanged: true,
+		},
+		{
+			name:    "unchanged",
+			content: "127.0.0.1\tlocalhost\n" + block,
+			block:   block,
+			want:    "127.0.0.1\tlocalhost\n" + block,
+		},
+		{
+			name:        "remove",
+			content:     "127.0.0.1\tlocalhost\n" + old + "::1\tlocalhost\n",
+			want:        "127.0.0.1\tlocalhost\n::1\tlocalhost\n",
+			wantChanged: true,
+		},
+		{
+			name:    "remove without a block",
+			content: "127.0.0.1\tlocalhost\n",
+			want:    "127.0.0.1\tlocalhost\n",
+		},
+		{
+			name:        "crlf",
+			content:     "127.0.0.1\tlocalhost\r\n",
+			block:       block,
+			want:        "127.0.0.1\tlocalhost\r\n" + beginMarker + "\r\n127.0.0.1\tapi.127.0.0.1.nip.io\r\n" + endMarker + "\r\n",
+			wantChanged: true,
+		},
+		{
+			name:    "incomplete block",
+			content: "127.0.0.1\tlocalhost\n" + beginMarker + "\n127.0.0.1\told.127.0.0.1.nip.io\n",
+			block:   block,
+			want:    "127.0.0.1\tlocalhost\n" + beginMarker + "\n127.0.0.1\told.127.0.0.1.nip.io\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hosts")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			changed, err := rewrite(path, tt.block)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("got changed %v, want %v", changed, tt.wantChanged)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package hostdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/minc-org/minc/pkg/exec"
+)
+
+// ErrUnsupported is returned when the host's resolver can not forward a
+// domain to the embedded DNS server
+var ErrUnsupported = errors.New("split DNS is not supported on this host")
+
+const (
+	// resolvedDropIn forwards the domain to the server with systemd-resolved
+	resolvedDropIn = "/etc/systemd/resolved.conf.d/minc.conf"
+	// resolverDir holds the macOS per-domain resolver files, see resolver(5)
+	resolverDir = "/etc/resolver"
+)
+
+// ResolverConfig returns the file ConfigureResolver writes for domain
+func ResolverConfig(domain string) (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return resolvedDropIn, nil
+	case "darwin":
+		return filepath.Join(resolverDir, domain), nil
+	default:
+		return "", fmt.Errorf("%w, use 'minc dns hosts' instead", ErrUnsupported)
+	}
+}
+
+// ConfigureResolver makes the host's resolver send the queries for domain,
+// and only those, to the DNS server at listen: a systemd-resolved drop-in on
+// Linux, a resolver(5) file on macOS. It returns the file written.
+func ConfigureResolver(ctx context.Context, domain, listen string) (string, error) {
+	path, err := ResolverConfig(domain)
+	if err != nil {
+		return "", err
+	}
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", fmt.Errorf("invalid DNS server address %q: %w", listen, err)
+	}
+	var content string
+	switch runtime.GOOS {
+	case "linux":
+		// the ~ makes the domain a routing-only domain, not a search domain
+		content = fmt.Sprintf("[Resolve]\nDNS=%s\nDomains=~%s\n", listen, domain)
+	case "darwin":
+		content = fmt.Sprintf("nameserver %s\nport %s\n", host, port)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return path, reloadResolver(ctx)
+}
+
+// UnconfigureResolver removes the file ConfigureResolver wrote for domain
+func UnconfigureResolver(ctx context.Context, domain string) error {
+	path, err := ResolverConfig(domain)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return reloadResolver(ctx)
+}
+
+// reloadResolver restarts systemd-resolved to read its drop-ins, macOS reads
+// /etc/resolver by itself
+func reloadResolver(ctx context.Context) error {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	if out, err := exec.CombinedOutputLines(exec.CommandContext(ctx, "systemctl", "try-restart", "systemd-resolved")); err != nil {
+		return fmt.Errorf("restarting systemd-resolved: %w: %v", err, out)
+	}
+	return nil
+}
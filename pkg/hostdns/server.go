@@ -0,0 +1,146 @@
+package hostdns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/minc-org/minc/pkg/log"
+)
+
+// DefaultListen is the address of the embedded DNS server, off port 53 to not
+// clash with the host's resolver and off 5353, which mDNS responders bind
+const DefaultListen = "127.0.0.1:1053"
+
+// ttl of the answers, in seconds; the addresses never change
+const ttl = 300
+
+// DNS message constants, RFC 1035
+const (
+	headerLen = 12
+	typeA     = 1
+	classIN   = 1
+
+	rcodeFormErr = 1
+	rcodeNotImp  = 4
+	rcodeRefused = 5
+)
+
+// Server is a DNS server answering A queries for Domain and all its
+// subdomains with 127.0.0.1, like nip.io does for constants.HostName
+type Server struct {
+	Domain string
+}
+
+// ListenAndServe serves DNS over UDP on addr until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	buf := make([]byte, 512)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			log.Debug("reading a DNS query", "err", err)
+			continue
+		}
+		resp := s.answer(buf[:n])
+		if resp == nil {
+			continue
+		}
+		if _, err := conn.WriteTo(resp, from); err != nil {
+			log.Debug("writing a DNS response", "to", from, "err", err)
+		}
+	}
+}
+
+// answer returns the response to the query, nil for messages not worth one
+func (s *Server) answer(query []byte) []byte {
+	if len(query) < headerLen {
+		return nil
+	}
+	flags := binary.BigEndian.Uint16(query[2:4])
+	// responses, e.g. reflected ones, are never answered
+	if flags&0x8000 != 0 {
+		return nil
+	}
+	// QR, AA, the query's opcode and RD
+	respFlags := uint16(0x8000|0x0400) | flags&0x7900
+	if opcode := flags >> 11 & 0xf; opcode != 0 || binary.BigEndian.Uint16(query[4:6]) != 1 {
+		return response(query[:headerLen], respFlags|rcodeNotImp, nil, nil)
+	}
+	name, end, err := parseName(query, headerLen)
+	if err != nil || end+4 > len(query) {
+		return response(query[:headerLen], respFlags|rcodeFormErr, nil, nil)
+	}
+	question := query[headerLen : end+4]
+	qtype := binary.BigEndian.Uint16(query[end : end+2])
+	qclass := binary.BigEndian.Uint16(query[end+2 : end+4])
+	domain := strings.ToLower(strings.TrimSuffix(s.Domain, "."))
+	if name != domain && !strings.HasSuffix(name, "."+domain) {
+		return response(query[:headerLen], respFlags|rcodeRefused, question, nil)
+	}
+	// the name exists for every type, only A has an answer
+	if qclass != classIN || qtype != typeA {
+		return response(query[:headerLen], respFlags, question, nil)
+	}
+	ip := netip.MustParseAddr(loopback).As4()
+	// a pointer to the question's name, type A, class IN, TTL and the address
+	answer := []byte{0xc0, headerLen, 0, typeA, 0, classIN}
+	answer = binary.BigEndian.AppendUint32(answer, ttl)
+	answer = append(answer, 0, 4)
+	answer = append(answer, ip[:]...)
+	return response(query[:headerLen], respFlags, question, answer)
+}
+
+// response builds a message with the query's ID, flags and at most one
+// question and answer
+func response(header []byte, flags uint16, question, answer []byte) []byte {
+	msg := make([]byte, headerLen, headerLen+len(question)+len(answer))
+	copy(msg[:2], header[:2])
+	binary.BigEndian.PutUint16(msg[2:4], flags)
+	if question != nil {
+		binary.BigEndian.PutUint16(msg[4:6], 1)
+	}
+	if answer != nil {
+		binary.BigEndian.PutUint16(msg[6:8], 1)
+	}
+	msg = append(msg, question...)
+	return append(msg, answer...)
+}
+
+// parseName reads the uncompressed name at off, queries do not compress their
+// only name. It returns the lowercase name and the offset after it.
+func parseName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	for {
+		if off >= len(msg) {
+			return "", 0, fmt.Errorf("truncated name")
+		}
+		l := int(msg[off])
+		off++
+		if l == 0 {
+			return strings.ToLower(strings.Join(labels, ".")), off, nil
+		}
+		if l > 63 || off+l > len(msg) {
+			return "", 0, fmt.Errorf("invalid label")
+		}
+		labels = append(labels, string(msg[off:off+l]))
+		off += l
+	}
+}
@@ -0,0 +1,109 @@
+package hostdns
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// query returns a DNS query with ID 0x1234 and RD set for name and qtype
+func query(name string, qtype uint16) []byte {
+	msg := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range strings.Split(name, ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	return binary.BigEndian.AppendUint16(msg, classIN)
+}
+
+func TestAnswer(t *testing.T) {
+	const typeAAAA = 28
+	s := &Server{Domain: "127.0.0.1.nip.io."}
+	tests := []struct {
+		name  string
+		query []byte
+		// rcode is the response code, answers the number of answers
+		rcode   uint16
+		answers uint16
+		// question is set when the response repeats the query's question
+		question bool
+	}{
+		{
+			name:     "A",
+			query:    query("hello.apps.127.0.0.1.nip.io", typeA),
+			answers:  1,
+			question: true,
+		},
+		{
+			name:     "A of the domain, mixed case",
+			query:    query("127.0.0.1.NIP.io", typeA),
+			answers:  1,
+			question: true,
+		},
+		{
+			name:     "AAAA",
+			query:    query("api.127.0.0.1.nip.io", typeAAAA),
+			question: true,
+		},
+		{
+			name:     "out of the domain",
+			query:    query("example.com", typeA),
+			rcode:    rcodeRefused,
+			question: true,
+		},
+		{
+			name:  "truncated",
+			query: query("api.127.0.0.1.nip.io", typeA)[:20],
+			rcode: rcodeFormErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.answer(tt.query)
+			if len(resp) < headerLen {
+				t.Fatalf("got response %x, want a DNS message", resp)
+			}
+			if !bytes.Equal(resp[:2], tt.query[:2]) {
+				t.Errorf("got ID %x, want %x", resp[:2], tt.query[:2])
+			}
+			flags := binary.BigEndian.Uint16(resp[2:4])
+			// QR, AA and RD copied from the query
+			if flags&0x8500 != 0x8500 {
+				t.Errorf("got flags %#04x, want QR, AA and RD set", flags)
+			}
+			if rcode := flags & 0xf; rcode != tt.rcode {
+				t.Errorf("got rcode %d, want %d", rcode, tt.rcode)
+			}
+			if n := binary.BigEndian.Uint16(resp[6:8]); n != tt.answers {
+				t.Errorf("got %d answers, want %d", n, tt.answers)
+			}
+			if !tt.question {
+				if len(resp) != headerLen {
+					t.Errorf("got %d bytes, want only the header", len(resp))
+				}
+				return
+			}
+			question := tt.query[headerLen:]
+			if !bytes.Equal(resp[headerLen:headerLen+len(question)], question) {
+				t.Errorf("the response does not repeat the question")
+			}
+			if tt.answers == 1 {
+				answer := resp[headerLen+len(question):]
+				if len(answer) != 16 || !bytes.Equal(answer[12:], []byte{127, 0, 0, 1}) {
+					t.Errorf("got answer %x, want 127.0.0.1", answer)
+				}
+			}
+		})
+	}
+}
+
+func TestAnswerIgnoresResponses(t *testing.T) {
+	q := query("api.127.0.0.1.nip.io", typeA)
+	q[2] |= 0x80
+	if resp := (&Server{Domain: "127.0.0.1.nip.io"}).answer(q); resp != nil {
+		t.Errorf("got response %x to a response", resp)
+	}
+}
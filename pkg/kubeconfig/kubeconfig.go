@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/minc-org/minc/pkg/constants"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/minc-org/minc/pkg/log"
 	"k8s.io/client-go/tools/clientcmd"
@@ -102,3 +104,116 @@ func RemoveClusterFromConfig() error {
 	log.Debug(fmt.Sprintf("Cluster %s removed successfully from kubeconfig\n", constants.ContainerName))
 	return nil
 }
+
+// CurrentContext returns the current context of the user's kubeconfig, empty
+// when there is no kubeconfig yet
+func CurrentContext() (string, error) {
+	config, err := clientcmd.LoadFromFile(getKubeConfigPath())
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return config.CurrentContext, nil
+}
+
+// UseContext makes name the current context of the user's kubeconfig, a
+// context that no longer exists is left unset
+func UseContext(name string) error {
+	kubeConfigPath := getKubeConfigPath()
+	config, err := clientcmd.LoadFromFile(kubeConfigPath)
+	if err != nil {
+		return err
+	}
+	if _, exists := config.Contexts[name]; !exists || config.CurrentContext == name {
+		return nil
+	}
+	config.CurrentContext = name
+	if err := clientcmd.WriteToFile(*config, kubeConfigPath); err != nil {
+		return fmt.Errorf("failed to save kubeconfig: %v", err)
+	}
+	return nil
+}
+
+// Entry describes the MicroShift cluster in the user's kubeconfig
+type Entry struct {
+	Path    string
+	Context string
+	Server  string
+}
+
+// GetEntry returns the MicroShift cluster entry merged by UpdateKubeConfig
+func GetEntry() (*Entry, error) {
+	kubeConfigPath := getKubeConfigPath()
+	config, err := clientcmd.LoadFromFile(kubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	cluster, exists := config.Clusters[constants.ContainerName]
+	if !exists {
+		return nil, fmt.Errorf("cluster %s not found in kubeconfig %s", constants.ContainerName, kubeConfigPath)
+	}
+	entry := &Entry{Path: kubeConfigPath, Server: cluster.Server}
+	for ctxName, ctx := range config.Contexts {
+		if ctx.Cluster == constants.ContainerName {
+			entry.Context = ctxName
+			break
+		}
+	}
+	return entry, nil
+}
+
+// Certificates returns the CA and client certificate data of the MicroShift
+// cluster entry merged by UpdateKubeConfig, keyed by kubeconfig field
+func Certificates() (map[string][]byte, error) {
+	kubeConfigPath := getKubeConfigPath()
+	config, err := clientcmd.LoadFromFile(kubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	cluster, exists := config.Clusters[constants.ContainerName]
+	if !exists {
+		return nil, fmt.Errorf("cluster %s not found in kubeconfig %s", constants.ContainerName, kubeConfigPath)
+	}
+	certs := map[string][]byte{}
+	if len(cluster.CertificateAuthorityData) > 0 {
+		certs["certificate-authority-data"] = cluster.CertificateAuthorityData
+	}
+	for _, ctx := range config.Contexts {
+		if ctx.Cluster != constants.ContainerName {
+			continue
+		}
+		if user, ok := config.AuthInfos[ctx.AuthInfo]; ok && len(user.ClientCertificateData) > 0 {
+			certs["client-certificate-data"] = user.ClientCertificateData
+		}
+		break
+	}
+	return certs, nil
+}
+
+// SANPath returns the file holding the kubeconfig of the API server's extra
+// subject alternative name san
+func SANPath(san string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	// IPv6 addresses hold colons, which Windows does not allow in file names
+	name := strings.ReplaceAll(san, ":", "_") + ".kubeconfig"
+	return filepath.Join(dir, "minc", "kubeconfigs", name), nil
+}
+
+// SaveSAN writes the kubeconfig of the extra SAN san and returns its path.
+// It is kept apart from the user's kubeconfig, its entries have the same names
+// as the merged ones.
+func SaveSAN(san string, config []byte) (string, error) {
+	path, err := SANPath(san)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, config, 0600)
+}
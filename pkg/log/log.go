@@ -1,23 +1,171 @@
 package log
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+
+	"github.com/minc-org/minc/pkg/redact"
+	"k8s.io/klog/v2"
 )
 
+// keepRunLogs is the number of per-run log files kept in the log directory
+const keepRunLogs = 20
+
 var logger *slog.Logger
 
-func SetLogger(level string) {
-	opts := &slog.HandlerOptions{
-		Level: parseLogLevel(level),
+// Options configures the logger. Console output goes to stderr at Level in
+// Format, while a log file always captures the debug stream.
+type Options struct {
+	Level string
+	// Format is text or json
+	Format string
+	// File is the debug log file, a per-run file in RunLogDir is used when empty
+	File string
+	// RunLogDir holds the per-run debug log files, no file is written when
+	// both File and RunLogDir are empty
+	RunLogDir string
+}
+
+func SetLogger(opts *Options) error {
+	newHandler, err := handlerFunc(opts.Format)
+	if err != nil {
+		return err
+	}
+	handlers := []slog.Handler{
+		newHandler(os.Stderr, &slog.HandlerOptions{Level: parseLogLevel(opts.Level)}),
+	}
+
+	logFile, err := openLogFile(opts)
+	if err != nil {
+		// logging to the console still works, so this is not fatal
+		fmt.Fprintf(os.Stderr, "warning: unable to open log file: %v\n", err)
+	}
+	if logFile != nil {
+		// the debug stream holds command output such as kubeconfigs
+		handlers = append(handlers, newHandler(&redactingWriter{w: logFile}, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	}
-	textHandler := slog.NewTextHandler(os.Stdout, opts) // Use nil for default options
-	// jsonHandler := slog.NewJSONHandler(os.Stdout, nil) // Uncomment for JSON logs
 
 	// Create a logger instance with the selected handler
-	logger = slog.New(textHandler)
-	logger.Debug("Setting up logger", "level", level)
+	logger = slog.New(&multiHandler{handlers: handlers})
+	// client-go logs through klog, route it through the same handlers
+	klog.SetSlogLogger(logger)
+	logger.Debug("Setting up logger", "level", opts.Level, "format", opts.Format)
+	if logFile != nil {
+		logger.Debug("Writing debug log", "file", logFile.Name())
+	}
+	return nil
+}
+
+func handlerFunc(format string) (func(io.Writer, *slog.HandlerOptions) slog.Handler, error) {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return func(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+			return slog.NewTextHandler(w, opts)
+		}, nil
+	case "json":
+		return func(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+			return slog.NewJSONHandler(w, opts)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, use text or json", format)
+	}
+}
+
+// openLogFile opens the debug log file, creating a per-run file and pruning
+// old ones when no explicit file is set.
+func openLogFile(opts *Options) (*os.File, error) {
+	path := opts.File
+	if path == "" {
+		if opts.RunLogDir == "" {
+			return nil, nil
+		}
+		if err := os.MkdirAll(opts.RunLogDir, 0700); err != nil {
+			return nil, err
+		}
+		// earlier versions created the directory world readable
+		if err := os.Chmod(opts.RunLogDir, 0700); err != nil {
+			return nil, err
+		}
+		pruneRunLogs(opts.RunLogDir)
+		path = filepath.Join(opts.RunLogDir,
+			fmt.Sprintf("minc-%s-%d.log", time.Now().Format("20060102-150405"), os.Getpid()))
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+}
+
+// redactingWriter masks secrets in what is written to w, the handlers write
+// one whole record at a time
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (r *redactingWriter) Write(b []byte) (int, error) {
+	if _, err := r.w.Write(redact.Redact(b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// pruneRunLogs removes the oldest per-run log files so that a new one keeps
+// the directory at keepRunLogs files.
+func pruneRunLogs(dir string) {
+	files, err := filepath.Glob(filepath.Join(dir, "minc-*.log"))
+	if err != nil || len(files) < keepRunLogs {
+		return
+	}
+	// the timestamped names sort chronologically
+	sort.Strings(files)
+	for _, f := range files[:len(files)-keepRunLogs+1] {
+		_ = os.Remove(f)
+	}
+}
+
+// multiHandler fans records out to all handlers enabled for their level
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+func (m *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m.handlers {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range m.handlers {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+func (m *multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+	return &multiHandler{handlers: handlers}
 }
 
 // parseLogLevel converts a string to slog.Level
@@ -58,5 +206,23 @@ func Warn(msg string, args ...any) {
 
 func Fatal(msg string, args ...any) {
 	logger.Error(msg, args...)
-	os.Exit(1)
+	Exit(1)
+}
+
+// exitHooks run before the process exits through Exit or Fatal
+var exitHooks []func()
+
+// OnExit registers fn to run when the process exits through Exit or Fatal,
+// which skip deferred calls
+func OnExit(fn func()) {
+	exitHooks = append(exitHooks, fn)
+}
+
+// Exit runs the functions registered with OnExit, the latest first, and exits
+// with code
+func Exit(code int) {
+	for i := len(exitHooks) - 1; i >= 0; i-- {
+		exitHooks[i]()
+	}
+	os.Exit(code)
 }
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunLog(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			testRunLog(t, format)
+		})
+	}
+}
+
+func testRunLog(t *testing.T, format string) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	if err := SetLogger(&Options{Level: "error", Format: format, RunLogDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	Debug("command output", "stdout", "users:\n- name: user\n  user:\n    client-key-data: c2VjcmV0\n")
+
+	files, err := filepath.Glob(filepath.Join(dir, "minc-*.log"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("got log files %q, %v, want one", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "c2VjcmV0") || !strings.Contains(string(data), `client-key-data: <redacted>\n"`) {
+		t.Errorf("the debug log is not redacted: %s", data)
+	}
+	if format == "json" {
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if !json.Valid([]byte(line)) {
+				t.Errorf("invalid JSON record %s", line)
+			}
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	for path, want := range map[string]os.FileMode{dir: 0700, files[0]: 0600} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s has mode %o, want %o", path, got, want)
+		}
+	}
+}
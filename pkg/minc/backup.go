@@ -0,0 +1,169 @@
+package minc
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/minc-org/minc/pkg/providers"
+	"github.com/minc-org/minc/pkg/providers/register"
+)
+
+// backupDir is where MicroShift keeps its backups inside the container
+const backupDir = "/var/lib/microshift-backups"
+
+// Backup backs up MicroShift's data with 'microshift backup' and streams the
+// backup to bType.File as a .tar.gz. The microshift service is stopped for the
+// backup and started again afterwards.
+func Backup(ctx context.Context, bType *types.BackupType, r progress.Reporter) (err error) {
+	p, err := runningCluster(ctx, bType.Provider)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(bType.File, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := f.Close(); err == nil {
+			err = cErr
+		}
+		if err != nil {
+			os.Remove(bType.File)
+		}
+	}()
+
+	if err := stopService(ctx, p, r); err != nil {
+		return err
+	}
+	defer startService(ctx, p, r)
+	name := fmt.Sprintf("minc-%s", time.Now().Format("20060102-150405"))
+	dir := path.Join(backupDir, name)
+	defer removeInContainer(ctx, p, dir)
+	err = progress.Run(r, progress.PhaseBackup, "Backing up the MicroShift data", func() error {
+		_, err := p.Exec(ctx, "microshift", "backup", dir)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return progress.Run(r, progress.PhaseCopy, fmt.Sprintf("Copying the backup to %s", bType.File), func() error {
+		zw := gzip.NewWriter(f)
+		if err := p.ExecStream(ctx, nil, zw, "tar", "-C", backupDir, "-cf", "-", name); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+}
+
+// Restore restores MicroShift's data from a backup written by Backup with
+// 'microshift restore', then waits for the cluster to come back.
+func Restore(ctx context.Context, bType *types.BackupType, r progress.Reporter) error {
+	f, err := os.Open(bType.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s is not a minc backup: %w", bType.File, err)
+	}
+	p, err := runningCluster(ctx, bType.Provider)
+	if err != nil {
+		return err
+	}
+
+	if err := stopService(ctx, p, r); err != nil {
+		return err
+	}
+	dir := path.Join(backupDir, fmt.Sprintf("minc-restore-%s", time.Now().Format("20060102-150405")))
+	defer removeInContainer(ctx, p, dir)
+	err = progress.Run(r, progress.PhaseCopy, fmt.Sprintf("Copying %s into the container", bType.File), func() error {
+		if _, err := p.Exec(ctx, "mkdir", "-p", dir); err != nil {
+			return err
+		}
+		// the archive holds the backup directory, whatever its name
+		return p.ExecStream(ctx, zr, nil, "tar", "-C", dir, "--strip-components=1", "-xf", "-")
+	})
+	if err == nil {
+		err = progress.Run(r, progress.PhaseRestore, "Restoring the MicroShift data", func() error {
+			_, err := p.Exec(ctx, "microshift", "restore", dir)
+			return err
+		})
+	}
+	// the service comes back with the previous data if the restore failed
+	if startErr := startService(ctx, p, r); err == nil {
+		err = startErr
+	}
+	if err != nil {
+		return err
+	}
+
+	cType := &types.CreateType{
+		ServiceWaitTimeout:  bType.ServiceWaitTimeout,
+		ServiceWaitInterval: bType.ServiceWaitInterval,
+	}
+	if err := waitForService(ctx, p, cType, r); err != nil {
+		return err
+	}
+	config, err := fetchKubeConfig(ctx, p, recordedAPISANs(), r)
+	if err != nil {
+		return err
+	}
+	return waitForPods(ctx, config, r)
+}
+
+// runningCluster returns the provider of a running cluster
+func runningCluster(ctx context.Context, provider string) (providers.Provider, error) {
+	p, err := register.Register(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Provider Info", "Provider", p)
+	out, _ := p.List(ctx)
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: use 'minc create' to create the cluster", providers.ErrNoSuchContainer)
+	}
+	if !strings.Contains(string(out), "running") {
+		return nil, fmt.Errorf("%w: use 'minc create' to start the cluster", providers.ErrContainerNotRunning)
+	}
+	return p, nil
+}
+
+func stopService(ctx context.Context, p providers.Provider, r progress.Reporter) error {
+	return progress.Run(r, progress.PhaseStopService, "Stopping the MicroShift service", func() error {
+		_, err := p.Exec(ctx, "systemctl", "stop", "microshift")
+		return err
+	})
+}
+
+// startService starts the microshift service, also after an interrupted
+// backup or restore
+func startService(ctx context.Context, p providers.Provider, r progress.Reporter) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopTimeout)
+	defer cancel()
+	err := progress.Run(r, progress.PhaseStartService, "Starting the MicroShift service", func() error {
+		_, err := p.Exec(ctx, "systemctl", "start", "microshift")
+		return err
+	})
+	if err != nil {
+		log.Error("failed to start the MicroShift service, check 'minc logs'", "err", err)
+	}
+	return err
+}
+
+// removeInContainer removes a temporary path inside the container
+func removeInContainer(ctx context.Context, p providers.Provider, dir string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopTimeout)
+	defer cancel()
+	if _, err := p.Exec(ctx, "rm", "-rf", dir); err != nil {
+		log.Warn("failed to remove the temporary backup in the container", "path", dir, "err", err)
+	}
+}
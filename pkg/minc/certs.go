@@ -0,0 +1,342 @@
+package minc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minc-org/minc/pkg/certs"
+	"github.com/minc-org/minc/pkg/cluster"
+	"github.com/minc-org/minc/pkg/clusterstate"
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/kubeconfig"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/minc-org/minc/pkg/providers"
+)
+
+const (
+	// routerNamespace holds the router and the secret of its default certificate
+	routerNamespace = "openshift-ingress"
+	// routerCertSecret is the router certificate signed by the local CA
+	routerCertSecret = "minc-router-certs"
+)
+
+// certsDir holds MicroShift's CAs and certificates, regenerated when missing
+var certsDir = path.Join(constants.UShiftDataDir, "certs")
+
+// CertsTrust adds the local CA, created on first use, to the host trust store
+// and makes a certificate it signs for the route domain the router's default
+// certificate. It returns the CA.
+func CertsTrust(ctx context.Context, cType *types.CertsType, r progress.Reporter) (*certs.CA, error) {
+	p, err := runningCluster(ctx, cType.Provider)
+	if err != nil {
+		return nil, err
+	}
+	ca, created, err := certs.LoadOrCreateCA()
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		log.Info("Created the local CA", "cert", ca.CertPath)
+	}
+	// first, a refused sudo fails before MicroShift is restarted
+	err = progress.Run(r, progress.PhaseTrustStore, "Adding the local CA to the host trust store", func() error {
+		return certs.Trust(ctx, ca)
+	})
+	if err != nil {
+		return nil, err
+	}
+	config, err := p.GetKubeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = progress.Run(r, progress.PhaseRouterCert, fmt.Sprintf("Installing the router certificate for *.%s", constants.RouteDomain), func() error {
+		cert, key, err := ca.Issue([]string{"*." + constants.RouteDomain, constants.RouteDomain})
+		if err != nil {
+			return err
+		}
+		if err := cluster.ApplyTLSSecret(ctx, config, routerNamespace, routerCertSecret, cert, key); err != nil {
+			return err
+		}
+		return p.ExecStream(ctx, bytes.NewReader(providers.IngressConfig(routerCertSecret)), nil,
+			"sh", "-c", "cat > "+providers.IngressConfigDropIn)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the drop-in is lost with the container, recreated ones get the
+	// setting from the recorded cluster settings
+	if _, err := recordRouterCert(routerCertSecret); err != nil {
+		r.Warn(fmt.Sprintf("the router certificate is lost when the container is recreated: %v", err))
+	}
+	return ca, restartService(ctx, p, cType, config, r)
+}
+
+// CertsUntrust removes the local CA from the host trust store and deletes it.
+// The router of a running cluster gets its default certificate back.
+func CertsUntrust(ctx context.Context, cType *types.CertsType, r progress.Reporter) error {
+	ca, err := certs.LoadCA()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if ca != nil {
+		err := progress.Run(r, progress.PhaseTrustStore, "Removing the local CA from the host trust store", func() error {
+			return certs.Untrust(ctx, ca)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// recreated containers mount the setting from the recorded cluster settings
+	recorded, err := recordRouterCert("")
+	if err != nil {
+		r.Warn(fmt.Sprintf("failed to update the recorded cluster settings: %v", err))
+	}
+	if p, err := runningCluster(ctx, cType.Provider); err != nil {
+		r.Warn(fmt.Sprintf("the router certificate was left unchanged: %v", err))
+	} else if _, err := p.Exec(ctx, "test", "-e", providers.IngressConfigDropIn); err == nil || recorded {
+		config, err := p.GetKubeConfig(ctx)
+		if err != nil {
+			return err
+		}
+		err = progress.Run(r, progress.PhaseRouterCert, "Restoring the default router certificate", func() error {
+			_, err := p.Exec(ctx, "rm", "-f", providers.IngressConfigDropIn)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if err := restartService(ctx, p, cType, config, r); err != nil {
+			return err
+		}
+		// the router stopped using the secret with the restart
+		if err := cluster.DeleteSecret(ctx, config, routerNamespace, routerCertSecret); err != nil {
+			return err
+		}
+	}
+	return certs.RemoveCA()
+}
+
+// recordRouterCert records the router certificate secret, empty for the
+// default certificate, in the cluster settings and renders it into the minc
+// config drop-in recreated containers mount. It reports whether a secret was
+// recorded before.
+func recordRouterCert(secret string) (bool, error) {
+	state, err := clusterstate.Load()
+	if err != nil {
+		return false, err
+	}
+	previous := state.Cluster.RouterCertSecret
+	if previous == secret {
+		return previous != "", nil
+	}
+	state.Cluster.RouterCertSecret = secret
+	if err := clusterstate.Save(state); err != nil {
+		return previous != "", err
+	}
+	_, err = providers.WriteMincConfig(state.Cluster)
+	return previous != "", err
+}
+
+// restartService restarts the microshift service to apply its configuration
+// and waits for the cluster to come back
+func restartService(ctx context.Context, p providers.Provider, cType *types.CertsType, config []byte, r progress.Reporter) error {
+	err := progress.Run(r, progress.PhaseRestartService, "Restarting the MicroShift service", func() error {
+		_, err := p.Exec(ctx, "systemctl", "restart", "microshift")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := waitForService(ctx, p, &types.CreateType{
+		ServiceWaitTimeout:  cType.ServiceWaitTimeout,
+		ServiceWaitInterval: cType.ServiceWaitInterval,
+	}, r); err != nil {
+		return err
+	}
+	return waitForPods(ctx, config, r)
+}
+
+// CertsStatus returns the expiry of the MicroShift certificates, of the
+// cluster entry of the kubeconfig and of the local CA, the first to expire first
+func CertsStatus(ctx context.Context, provider string) ([]certs.Certificate, error) {
+	p, err := runningCluster(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	list, err := clusterCerts(ctx, p, now)
+	if err != nil {
+		return nil, err
+	}
+	if entry, err := kubeconfig.GetEntry(); err != nil {
+		log.Warn("failed to read the cluster kubeconfig entry", "err", err)
+	} else if data, err := kubeconfig.Certificates(); err == nil {
+		fields := make([]string, 0, len(data))
+		for f := range data {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+		for _, f := range fields {
+			cert, err := certs.Inspect(fmt.Sprintf("%s (%s)", entry.Path, f), data[f], now)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, *cert)
+		}
+	}
+	if ca, err := certs.LoadCA(); err == nil {
+		cert, err := certs.Inspect(ca.CertPath, ca.Cert.Raw, now)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *cert)
+	}
+	certs.Sort(list)
+	return list, nil
+}
+
+// clusterCerts returns the certificates of the MicroShift certs directory
+func clusterCerts(ctx context.Context, p providers.Provider, now time.Time) ([]certs.Certificate, error) {
+	var archive bytes.Buffer
+	if err := p.ExecStream(ctx, nil, &archive, "tar", "-C", constants.UShiftDataDir, "-cf", "-", "certs"); err != nil {
+		return nil, fmt.Errorf("reading the MicroShift certificates: %w", err)
+	}
+	return certs.InspectTar(&archive, constants.UShiftDataDir, now)
+}
+
+// CertsRotate regenerates the MicroShift certificates that expire within
+// certs.ExpiringWithin: MicroShift creates the missing ones on start, signed
+// by its CAs. With cType.All the CAs are regenerated as well, which
+// invalidates every certificate and kubeconfig they signed. The kubeconfig is
+// refreshed afterwards and a failed rotation puts the previous certificates
+// back. It returns the paths of the rotated certificates, none when no
+// certificate expires soon.
+func CertsRotate(ctx context.Context, cType *types.CertsType, r progress.Reporter) ([]string, error) {
+	p, err := runningCluster(ctx, cType.Provider)
+	if err != nil {
+		return nil, err
+	}
+	// paths are relative to certsDir, "." is the whole directory
+	paths, rotated := []string{"."}, []string{certsDir}
+	if !cType.All {
+		if paths, rotated, err = expiringLeaves(ctx, p, r); err != nil || len(paths) == 0 {
+			return nil, err
+		}
+	}
+	previous := certsDir + ".minc-previous"
+	if err := stopService(ctx, p, r); err != nil {
+		return nil, err
+	}
+	err = progress.Run(r, progress.PhaseRotateCerts, "Moving the certificates to rotate aside", func() error {
+		if _, err := p.Exec(ctx, "rm", "-rf", previous); err != nil {
+			return err
+		}
+		return moveCerts(ctx, p, certsDir, previous, paths)
+	})
+	if err != nil {
+		restoreCerts(ctx, p, previous, paths)
+		return nil, err
+	}
+	wait := &types.CreateType{
+		ServiceWaitTimeout:  cType.ServiceWaitTimeout,
+		ServiceWaitInterval: cType.ServiceWaitInterval,
+	}
+	err = startService(ctx, p, r)
+	if err == nil {
+		err = waitForService(ctx, p, wait, r)
+	}
+	if err != nil {
+		restoreCerts(ctx, p, previous, paths)
+		return nil, err
+	}
+	removeInContainer(ctx, p, previous)
+	config, err := fetchKubeConfig(ctx, p, recordedAPISANs(), r)
+	if err != nil {
+		return nil, err
+	}
+	return rotated, waitForPods(ctx, config, r)
+}
+
+// expiringLeaves returns the certificate and key files, relative to certsDir,
+// of the expired and expiring certificates that are not CAs, and the paths of
+// those certificates. An expired CA needs a full rotation.
+func expiringLeaves(ctx context.Context, p providers.Provider, r progress.Reporter) ([]string, []string, error) {
+	list, err := clusterCerts(ctx, p, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	var files, rotated []string
+	for _, c := range list {
+		if c.Status == certs.StatusValid {
+			continue
+		}
+		if c.CA {
+			if c.Status == certs.StatusExpired {
+				return nil, nil, fmt.Errorf("the CA %s expired, regenerate all certificates with --all", c.Source)
+			}
+			r.Warn(fmt.Sprintf("the CA %s expires on %s, regenerate all certificates with --all", c.Source, c.NotAfter.Local().Format(time.DateOnly)))
+			continue
+		}
+		rel := strings.TrimPrefix(c.Source, certsDir+"/")
+		files = append(files, rel)
+		rotated = append(rotated, c.Source)
+		key := strings.TrimSuffix(rel, ".crt") + ".key"
+		if _, err := p.Exec(ctx, "test", "-e", path.Join(certsDir, key)); err == nil {
+			files = append(files, key)
+		}
+	}
+	return files, rotated, nil
+}
+
+// moveCerts moves the paths, relative to the from directory, into the to
+// directory keeping their layout
+func moveCerts(ctx context.Context, p providers.Provider, from, to string, paths []string) error {
+	for _, rel := range paths {
+		if rel == "." {
+			if _, err := p.Exec(ctx, "mv", from, to); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := p.Exec(ctx, "mkdir", "-p", path.Dir(path.Join(to, rel))); err != nil {
+			return err
+		}
+		if _, err := p.Exec(ctx, "mv", path.Join(from, rel), path.Join(to, rel)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreCerts puts the certificates CertsRotate moved aside back, also
+// after an interrupted rotation
+func restoreCerts(ctx context.Context, p providers.Provider, previous string, paths []string) {
+	log.Warn("Rotating the certificates failed, restoring the previous ones")
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopTimeout)
+	defer cancel()
+	commands := [][]string{{"systemctl", "stop", "microshift"}}
+	for _, rel := range paths {
+		moved, current := path.Join(previous, rel), path.Join(certsDir, rel)
+		// a failed move leaves the certificates in place
+		if _, err := p.Exec(ctx, "test", "-e", moved); err != nil {
+			continue
+		}
+		commands = append(commands, []string{"rm", "-rf", current}, []string{"mkdir", "-p", path.Dir(current)}, []string{"mv", moved, current})
+	}
+	commands = append(commands, []string{"systemctl", "start", "microshift"})
+	for _, command := range commands {
+		if _, err := p.Exec(ctx, command...); err != nil {
+			log.Error("failed to restore the previous certificates", "command", command, "err", err)
+			return
+		}
+	}
+}
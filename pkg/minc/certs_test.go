@@ -0,0 +1,151 @@
+package minc
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"maps"
+	"math/big"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/minc-org/minc/pkg/exec"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/minc-org/minc/pkg/providers/podman"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.SetLogger(&log.Options{Level: "error"}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// certPEM returns a self-signed PEM certificate expiring at notAfter
+func certPEM(t *testing.T, notAfter time.Time, isCA bool) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func certsArchive(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	// sorted like tar lists a directory, for a stable order
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		data := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestExpiringLeaves(t *testing.T) {
+	now := time.Now()
+	valid, expiring, expired := now.Add(365*24*time.Hour), now.Add(24*time.Hour), now.Add(-time.Hour)
+	tests := []struct {
+		name        string
+		files       map[string][]byte
+		keys        []string
+		wantFiles   []string
+		wantRotated []string
+		wantErr     bool
+	}{
+		{
+			name: "leaves",
+			files: map[string][]byte{
+				"certs/ca-bundle/ca-bundle.crt":            certPEM(t, valid, true),
+				"certs/kube-apiserver/server.crt":          certPEM(t, expiring, false),
+				"certs/kube-apiserver/server.key":          []byte("key"),
+				"certs/admin-kubeconfig-signer/ca.crt":     certPEM(t, valid, true),
+				"certs/admin-kubeconfig/client.crt":        certPEM(t, expired, false),
+				"certs/kubelet/kubelet-client-current.crt": certPEM(t, valid, false),
+			},
+			keys: []string{"kube-apiserver/server.key"},
+			wantFiles: []string{
+				"admin-kubeconfig/client.crt",
+				"kube-apiserver/server.crt", "kube-apiserver/server.key",
+			},
+			wantRotated: []string{
+				"/var/lib/microshift/certs/admin-kubeconfig/client.crt",
+				"/var/lib/microshift/certs/kube-apiserver/server.crt",
+			},
+		},
+		{
+			name: "all valid",
+			files: map[string][]byte{
+				"certs/admin-kubeconfig-signer/ca.crt": certPEM(t, valid, true),
+				"certs/kube-apiserver/server.crt":      certPEM(t, valid, false),
+			},
+		},
+		{
+			name: "expiring CA",
+			files: map[string][]byte{
+				"certs/admin-kubeconfig-signer/ca.crt": certPEM(t, expiring, true),
+			},
+		},
+		{
+			name: "expired CA",
+			files: map[string][]byte{
+				"certs/admin-kubeconfig-signer/ca.crt": certPEM(t, expired, true),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := exec.NewFakeCmder()
+			f.Expect("podman", "info", "--format", "{{.Host.Security.Rootless}}").Return("false\n")
+			f.Expect("podman", "info", "--format", "json").Return(`{"host":{"cgroupVersion":"v2","security":{"rootless":false}}}`)
+			f.Expect("podman", "exec", "microshift", "tar", "-C", "/var/lib/microshift", "-cf", "-", "certs").Return(certsArchive(t, tt.files))
+			for _, key := range tt.keys {
+				f.Expect("podman", "exec", "microshift", "test", "-e", "/var/lib/microshift/certs/"+key)
+			}
+			f.Expect("podman", "exec", "microshift", "test", "-e", exec.Wildcard).Fail(1, "")
+			p, err := podman.New(context.Background(), f, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files, rotated, err := expiringLeaves(context.Background(), p, progress.New(os.Stderr, true))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(files, tt.wantFiles) || !reflect.DeepEqual(rotated, tt.wantRotated) {
+				t.Errorf("got %q, %q, want %q, %q", files, rotated, tt.wantFiles, tt.wantRotated)
+			}
+		})
+	}
+}
@@ -1,59 +1,325 @@
 package minc
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/minc-org/minc/pkg/cluster"
+	"github.com/minc-org/minc/pkg/clusterstate"
 	"github.com/minc-org/minc/pkg/constants"
 	"github.com/minc-org/minc/pkg/kubeconfig"
 	"github.com/minc-org/minc/pkg/log"
 	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/minc-org/minc/pkg/providers"
 	"github.com/minc-org/minc/pkg/providers/register"
-	"github.com/minc-org/minc/pkg/spinner"
+	"github.com/minc-org/minc/pkg/reference"
+	"github.com/minc-org/minc/pkg/retry"
+	"github.com/minc-org/minc/pkg/signature"
+	"github.com/minc-org/minc/pkg/snapshot"
 )
 
-func Create(cType *types.CreateType) error {
-	p, err := register.Register(cType.Provider)
+// stopTimeout bounds stopping the container after an interrupted create
+const stopTimeout = 30 * time.Second
+
+// Create creates and starts the MicroShift cluster, reporting each phase to r.
+func Create(ctx context.Context, cType *types.CreateType, r progress.Reporter) (err error) {
+	p, err := register.Register(ctx, cType.Provider)
 	if err != nil {
 		return err
 	}
 	log.Debug("Provider Info", "Provider", p)
-	img := constants.GetUShiftImage(cType.UShiftImage, cType.UShiftVersion)
-	log.Info(fmt.Sprintf("Ensuring cluster image (%s) ...", img))
-	s := spinner.New(time.Second)
-	s.Start()
-	if err := p.PullImage(img); err != nil {
+	var snap *snapshot.Snapshot
+	if cType.FromSnapshot != "" {
+		if snap, err = loadSnapshot(ctx, p, cType); err != nil {
+			return err
+		}
+	}
+	img := cType.ImageRef()
+	if err := checkImageRef(img, cType, snap); err != nil {
 		return err
 	}
-	s.Stop()
-
-	s.Start()
-	if err := p.Create(cType); err != nil {
+	if err := checkSignatureOptions(cType, snap != nil); err != nil {
 		return err
 	}
-	s.Stop()
+	if err := checkNetworkOptions(cType); err != nil {
+		return err
+	}
+	if !cType.SkipPreflight {
+		err := progress.Run(r, progress.PhasePreflight, "Running preflight checks", func() error {
+			return preflightCreate(ctx, p, cType, r)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if snap == nil {
+		err = progress.Run(r, progress.PhasePull, fmt.Sprintf("Ensuring cluster image (%s)", img), func() error {
+			if err := pullImage(ctx, p, img, cType, progress.Writer(r)); err != nil {
+				return err
+			}
+			if cType.VerifyDigest == "" {
+				return nil
+			}
+			r.Update(fmt.Sprintf("verifying the image digest is %s", cType.VerifyDigest))
+			return verifyDigest(ctx, p, img, cType.VerifyDigest)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	var verification *signature.Result
+	if cType.SignatureKey != "" || cType.SignaturePolicy != "" {
+		err = progress.Run(r, progress.PhaseVerify, "Verifying the image signature", func() error {
+			verification, err = verifySignature(ctx, p, img, cType)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
 
-	log.Info("Waiting for MicroShift service to start...")
-	s.Start()
-	if err := p.WaitForMicroShiftService(); err != nil {
+	// Everything created from here on is recorded and undone if create fails
+	rb := &rollback{operation: "Create"}
+	existed := clusterExists(ctx, p)
+	defer func() {
+		if err == nil {
+			return
+		}
+		if cType.KeepOnFailure {
+			log.Warn("Keeping the partially created cluster, use 'minc delete' to remove it")
+		} else {
+			rb.run()
+		}
+		// a kept or pre-existing container is left in place, but must not keep
+		// starting up after an interrupted create
+		if ctx.Err() != nil && (cType.KeepOnFailure || existed) {
+			stopInterrupted(p)
+		}
+	}()
+	if cType.DisableOverlayCache && !p.VolumeExists(ctx, constants.StorageVolume) {
+		rb.add("container storage volume", func(ctx context.Context) error {
+			if !p.VolumeExists(ctx, constants.StorageVolume) {
+				return nil
+			}
+			return p.DeleteVolume(ctx, constants.StorageVolume)
+		})
+	}
+	if cType.PersistentData && !existed {
+		if p.VolumeExists(ctx, constants.DataVolume) {
+			log.Info(fmt.Sprintf("Reusing the MicroShift data in volume %s, remove it with 'minc delete' for a fresh cluster", constants.DataVolume))
+		} else {
+			rb.add("data volume", func(ctx context.Context) error {
+				if !p.VolumeExists(ctx, constants.DataVolume) {
+					return nil
+				}
+				return p.DeleteVolume(ctx, constants.DataVolume)
+			})
+		}
+	}
+	networkCreated := false
+	if !existed {
+		err := progress.Run(r, progress.PhaseCreate, "Creating the MicroShift container", func() error {
+			if cType.Network != "" {
+				created, err := ensureNetwork(ctx, p, cType, r)
+				if err != nil {
+					return err
+				}
+				networkCreated = created
+				if created {
+					rb.add("network", func(ctx context.Context) error {
+						return p.DeleteNetwork(ctx, cType.Network)
+					})
+				}
+			}
+			rb.add("container", func(ctx context.Context) error {
+				if !clusterExists(ctx, p) {
+					return nil
+				}
+				return p.Delete(ctx)
+			})
+			return p.Create(ctx, cType)
+		})
+		if err != nil {
+			return err
+		}
+		if snap != nil {
+			err := progress.Run(r, progress.PhaseImportVolumes, "Restoring the snapshot volumes", func() error {
+				return importVolumes(ctx, p, snap, r)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = progress.Run(r, progress.PhaseStart, "Starting the MicroShift container", func() error {
+		return p.Start(ctx)
+	})
+	if err != nil {
 		return err
 	}
-	s.Stop()
 
-	log.Info("Waiting for KubeConfig ...")
-	s.Start()
-	config, err := p.GetKubeConfig()
+	if err := waitForService(ctx, p, cType, r); err != nil {
+		return err
+	}
+	// the entry of an existing cluster is kept, its kubeconfig stays valid
+	if !existed {
+		previousContext, err := kubeconfig.CurrentContext()
+		if err != nil {
+			return err
+		}
+		rb.add("kubeconfig entry", func(ctx context.Context) error {
+			if err := kubeconfig.RemoveClusterFromConfig(); err != nil {
+				return err
+			}
+			return kubeconfig.UseContext(previousContext)
+		})
+	}
+	config, err := fetchKubeConfig(ctx, p, cType.APISANs, r)
 	if err != nil {
 		return err
 	}
-	s.Stop()
-	if err := kubeconfig.UpdateKubeConfig(config); err != nil {
+	if err := waitForPods(ctx, config, r); err != nil {
 		return err
 	}
-	log.Info("Waiting for pods to be ready...")
-	if err := cluster.GetPodStatus(config); err != nil {
+	if !existed {
+		if err := clusterstate.Save(&clusterstate.State{Created: time.Now(), Cluster: cType, Signature: verification, NetworkCreated: networkCreated}); err != nil {
+			log.Warn("failed to record the cluster settings", "err", err)
+		}
+	}
+	return nil
+}
+
+// checkImageRef validates the image reference and the digest it must have
+func checkImageRef(img string, cType *types.CreateType, snap *snapshot.Snapshot) error {
+	// report an invalid --microshift-image as given, not with the version appended
+	if cType.Image == "" && cType.UShiftImage != "" {
+		if _, err := reference.Parse(cType.UShiftImage); err != nil {
+			return err
+		}
+	}
+	ref, err := reference.Parse(img)
+	if err != nil {
+		return err
+	}
+	if cType.VerifyDigest == "" {
+		return nil
+	}
+	if snap != nil {
+		return fmt.Errorf("--verify-digest can not be used with --from-snapshot, snapshot images are local")
+	}
+	if err := reference.ValidateDigest(cType.VerifyDigest); err != nil {
 		return err
 	}
+	if ref.Digest != "" && ref.Digest != cType.VerifyDigest {
+		return fmt.Errorf("%w: %s is pinned to another digest than %s", reference.ErrDigestMismatch, img, cType.VerifyDigest)
+	}
 	return nil
 }
+
+// verifyDigest checks one of the repository digests of the pulled image is
+// digest, a manifest list digest for multi-arch images
+func verifyDigest(ctx context.Context, p providers.Provider, img, digest string) error {
+	digests, err := p.ImageDigests(ctx, img)
+	if err != nil {
+		return err
+	}
+	for _, d := range digests {
+		if _, got, _ := strings.Cut(d, "@"); got == digest {
+			return nil
+		}
+	}
+	if len(digests) == 0 {
+		return fmt.Errorf("%w: %s has no repository digest, it was not pulled from a registry", reference.ErrDigestMismatch, img)
+	}
+	return fmt.Errorf("%w: %s has the digests %s, expected %s", reference.ErrDigestMismatch, img, strings.Join(digests, ", "), digest)
+}
+
+// waitForService waits for the microshift unit of the started container,
+// showing the tail of its journal when it does not come up
+func waitForService(ctx context.Context, p providers.Provider, cType *types.CreateType, r progress.Reporter) error {
+	backoff := providers.ServiceWaitBackoff(cType.ServiceWaitTimeout, cType.ServiceWaitInterval)
+	onAttempt := backoff.OnAttempt
+	backoff.OnAttempt = func(a retry.Attempt) {
+		onAttempt(a)
+		if a.NextDelay > 0 {
+			r.Update(fmt.Sprintf("check %d: not active yet, next in %s", a.Number, a.NextDelay.Round(100*time.Millisecond)))
+		}
+	}
+	err := progress.Run(r, progress.PhaseWaitService, "Waiting for the MicroShift service", func() error {
+		return p.WaitForMicroShiftService(ctx, backoff)
+	})
+	if err != nil && ctx.Err() == nil {
+		dumpMicroShiftJournal(ctx, p, os.Stderr)
+	}
+	return err
+}
+
+// fetchKubeConfig merges the cluster's kubeconfig into the user's and returns
+// it. The kubeconfigs of the extra API server SANs are saved next to it.
+func fetchKubeConfig(ctx context.Context, p providers.Provider, apiSANs []string, r progress.Reporter) ([]byte, error) {
+	var config []byte
+	err := progress.Run(r, progress.PhaseKubeConfig, "Updating the kubeconfig", func() error {
+		var err error
+		if config, err = p.GetKubeConfig(ctx); err != nil {
+			return err
+		}
+		if err := kubeconfig.UpdateKubeConfig(config); err != nil {
+			return err
+		}
+		paths, err := saveSANKubeConfigs(ctx, p, apiSANs)
+		for i, path := range paths {
+			r.Update(fmt.Sprintf("kubeconfig for %s: %s", apiSANs[i], path))
+		}
+		return err
+	})
+	return config, err
+}
+
+// saveSANKubeConfigs saves the kubeconfig MicroShift generates for each extra
+// API server SAN and returns their paths
+func saveSANKubeConfigs(ctx context.Context, p providers.Provider, apiSANs []string) ([]string, error) {
+	var paths []string
+	for _, san := range apiSANs {
+		config, err := p.Exec(ctx, "cat", providers.KubeConfigPath(san))
+		if err != nil {
+			return paths, fmt.Errorf("reading the kubeconfig of --api-san %s: %w", san, err)
+		}
+		path, err := kubeconfig.SaveSAN(san, config)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// recordedAPISANs returns the extra API server SANs the cluster was created
+// with, none when its settings were not recorded
+func recordedAPISANs() []string {
+	state, err := clusterstate.Load()
+	if err != nil || state.Cluster == nil {
+		return nil
+	}
+	return state.Cluster.APISANs
+}
+
+func waitForPods(ctx context.Context, config []byte, r progress.Reporter) error {
+	return progress.Run(r, progress.PhaseWaitPods, "Waiting for pods to be ready", func() error {
+		return cluster.GetPodStatus(ctx, config)
+	})
+}
+
+// stopInterrupted stops the MicroShift container after create was cancelled
+// or timed out. It uses its own context since the create context is done.
+func stopInterrupted(p providers.Provider) {
+	log.Warn("Create interrupted, stopping the MicroShift container ...")
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	if err := p.Stop(ctx); err != nil {
+		log.Error("failed to stop the MicroShift container", "err", err)
+	}
+}
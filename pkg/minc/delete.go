@@ -1,23 +1,96 @@
 package minc
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/minc-org/minc/pkg/clusterstate"
+	"github.com/minc-org/minc/pkg/constants"
 	"github.com/minc-org/minc/pkg/kubeconfig"
 	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/minc-org/minc/pkg/providers"
 	"github.com/minc-org/minc/pkg/providers/register"
 )
 
-func Delete(provider string) error {
-	p, err := register.Register(provider)
+// Delete deletes the MicroShift cluster, reporting each phase to r.
+func Delete(ctx context.Context, dType *types.DeleteType, r progress.Reporter) error {
+	if dType.KeepData && dType.Purge {
+		return fmt.Errorf("--keep-data and --purge can not be used together")
+	}
+	p, err := register.Register(ctx, dType.Provider)
 	if err != nil {
 		return err
 	}
 	log.Debug("Provider Info", "Provider", p)
-	if err := p.Delete(); err != nil {
-		return err
+	volumes := deletedVolumes(ctx, p, dType)
+	// read before the state is removed, without it the network is kept
+	state, err := clusterstate.Load()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn("failed to read the recorded cluster settings", "err", err)
 	}
-	log.Info("Removing entry from kubeconfig ...")
-	if err := kubeconfig.RemoveClusterFromConfig(); err != nil {
+	err = progress.Run(r, progress.PhaseDelete, "Deleting the MicroShift container", func() error {
+		err := p.Delete(ctx)
+		// the volumes of an already deleted container can still be removed
+		if errors.Is(err, providers.ErrNoSuchContainer) && len(volumes) > 0 {
+			r.Update("the container is already deleted")
+			return nil
+		}
 		return err
+	})
+	if err != nil {
+		return err
+	}
+	deleteNetwork(ctx, p, state, r)
+	if err := clusterstate.Remove(); err != nil {
+		r.Warn(fmt.Sprintf("failed to remove the recorded cluster settings: %v", err))
+	}
+	if len(volumes) > 0 {
+		err := progress.Run(r, progress.PhaseDeleteVolumes, "Deleting the MicroShift volumes", func() error {
+			for _, v := range volumes {
+				r.Update(v)
+				if err := p.DeleteVolume(ctx, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return progress.Run(r, progress.PhaseRemoveKubeConfig, "Removing entry from kubeconfig", func() error {
+		return kubeconfig.RemoveClusterFromConfig()
+	})
+}
+
+// deletedVolumes returns the existing volumes delete removes: the data volume
+// unless kept, and with purge the storage volume and every volume the
+// container mounts.
+func deletedVolumes(ctx context.Context, p providers.Provider, dType *types.DeleteType) []string {
+	var candidates []string
+	if !dType.KeepData {
+		candidates = append(candidates, constants.DataVolume)
+	}
+	if dType.Purge {
+		candidates = append(candidates, constants.StorageVolume)
+		if inspect, err := p.Inspect(ctx); err == nil {
+			mounts, _ := providers.VolumeMounts(inspect)
+			for _, m := range mounts {
+				candidates = append(candidates, m.Name)
+			}
+		}
+	}
+	var volumes []string
+	seen := map[string]bool{}
+	for _, v := range candidates {
+		if !seen[v] && p.VolumeExists(ctx, v) {
+			volumes = append(volumes, v)
+		}
+		seen[v] = true
 	}
-	return nil
+	return volumes
 }
@@ -0,0 +1,140 @@
+package minc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minc-org/minc/pkg/cluster"
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/diagnose"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/providers"
+	"github.com/minc-org/minc/pkg/providers/register"
+)
+
+// microShiftConfigDir holds config.yaml and the config.d drop-ins inside the container
+const microShiftConfigDir = "/etc/microshift"
+
+// Diagnose collects everything useful for a bug report into a support tarball.
+// Failing collectors are recorded in the bundle and do not abort it.
+func Diagnose(ctx context.Context, dType *types.DiagnoseType) (err error) {
+	b, err := diagnose.New(dType.Output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := b.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if err := b.Add("version.txt", []byte(fmt.Sprintf("version: %s\n", constants.Version))); err != nil {
+		return err
+	}
+	if err := b.Collect("config.json", func() ([]byte, error) { return json.MarshalIndent(dType.Config, "", "  ") }); err != nil {
+		return err
+	}
+	if err := collectRootlessDropIns(ctx, b); err != nil {
+		return err
+	}
+
+	p, err := register.Register(ctx, dType.Provider)
+	if err != nil {
+		b.RecordError("provider", err)
+		return nil
+	}
+	log.Debug("Provider Info", "Provider", p)
+	return collectProvider(ctx, b, p)
+}
+
+func collectProvider(ctx context.Context, b *diagnose.Bundle, p providers.Provider) error {
+	collectors := []struct {
+		name string
+		fn   func() ([]byte, error)
+	}{
+		{"provider-info.json", func() ([]byte, error) { return p.RawInfo(ctx) }},
+		{"container-inspect.json", func() ([]byte, error) { return p.Inspect(ctx) }},
+		{"journal-microshift.log", journalCollector(ctx, p, "microshift")},
+		{"journal-crio.log", journalCollector(ctx, p, "crio")},
+		{"crictl-ps.txt", func() ([]byte, error) { return p.Exec(ctx, "crictl", "ps", "-a") }},
+	}
+	for _, c := range collectors {
+		if err := b.Collect(c.name, c.fn); err != nil {
+			return err
+		}
+	}
+	if err := collectMicroShiftConfig(ctx, b, p); err != nil {
+		return err
+	}
+
+	config, err := p.GetKubeConfig(ctx)
+	if err != nil {
+		b.RecordError("cluster", err)
+		return nil
+	}
+	dump, err := cluster.Dump(ctx, config)
+	if err != nil {
+		b.RecordError("cluster", err)
+		return nil
+	}
+	for name, data := range dump {
+		if err := b.Add(filepath.ToSlash(filepath.Join("cluster", name)), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func journalCollector(ctx context.Context, p providers.Provider, unit string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		var buf bytes.Buffer
+		err := p.Logs(ctx, &buf, &types.LogsType{Component: unit})
+		return buf.Bytes(), err
+	}
+}
+
+// collectMicroShiftConfig adds every file below /etc/microshift in the container.
+func collectMicroShiftConfig(ctx context.Context, b *diagnose.Bundle, p providers.Provider) error {
+	out, err := p.Exec(ctx, "find", microShiftConfigDir, "-type", "f", "-print0")
+	if err != nil {
+		b.RecordError("microshift-config", err)
+		return nil
+	}
+	// NUL separated, file names may hold spaces and newlines
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file == "" {
+			continue
+		}
+		name := "microshift-config/" + strings.TrimPrefix(file, microShiftConfigDir+"/")
+		if err := b.Collect(name, func() ([]byte, error) { return p.Exec(ctx, "cat", file) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectRootlessDropIns adds the rootless MicroShift, CRI-O and crun drop-ins
+// minc writes to its config directory.
+func collectRootlessDropIns(ctx context.Context, b *diagnose.Bundle) error {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		b.RecordError("rootless", err)
+		return nil
+	}
+	for _, name := range []string{"rootless-microshift.yaml", "rootless-crio.conf", "crun-rootless"} {
+		path := filepath.Join(configDir, "minc", name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := b.Collect("rootless/"+name, func() ([]byte, error) { return os.ReadFile(path) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
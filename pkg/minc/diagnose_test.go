@@ -0,0 +1,89 @@
+package minc
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/minc-org/minc/pkg/diagnose"
+	"github.com/minc-org/minc/pkg/exec"
+	"github.com/minc-org/minc/pkg/providers/podman"
+)
+
+// bundleFiles returns the content of the files of the bundle at path
+func bundleFiles(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(data)
+	}
+}
+
+func TestCollectMicroShiftConfig(t *testing.T) {
+	f := exec.NewFakeCmder()
+	f.Expect("podman", "info", "--format", "{{.Host.Security.Rootless}}").Return("false\n")
+	f.Expect("podman", "info", "--format", "json").Return(`{"host":{"cgroupVersion":"v2","security":{"rootless":false}}}`)
+	f.Expect("podman", "exec", "microshift", "find", "/etc/microshift", "-type", "f", "-print0").
+		Return("/etc/microshift/config.yaml\x00/etc/microshift/config.d/10 my drop-in.yaml\x00/etc/microshift/broken.yaml\x00")
+	f.Expect("podman", "exec", "microshift", "cat", "/etc/microshift/config.yaml").Return("dns:\n  baseDomain: example.com\nstorage:\n  pullSecret: eyJhdXRocyI6e319\n")
+	f.Expect("podman", "exec", "microshift", "cat", "/etc/microshift/config.d/10 my drop-in.yaml").Return("network: {}\n")
+	f.Expect("podman", "exec", "microshift", "cat", "/etc/microshift/broken.yaml").Fail(1, "cat: Permission denied")
+	p, err := podman.New(context.Background(), f, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "minc-diagnose.tar.gz")
+	b, err := diagnose.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := collectMicroShiftConfig(context.Background(), b, p); err != nil {
+		t.Fatalf("collectMicroShiftConfig: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got := bundleFiles(t, path)
+	want := map[string]string{
+		// the bundle redacts the collected files
+		"minc-diagnose/microshift-config/config.yaml":                 "dns:\n  baseDomain: example.com\nstorage:\n  pullSecret: <redacted>\n",
+		"minc-diagnose/microshift-config/config.d/10 my drop-in.yaml": "network: {}\n",
+	}
+	errs := got["minc-diagnose/errors.txt"]
+	delete(got, "minc-diagnose/errors.txt")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got files %q, want %q", got, want)
+	}
+	if errs == "" {
+		t.Errorf("the failed cat is not recorded in errors.txt")
+	}
+}
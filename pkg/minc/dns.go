@@ -0,0 +1,84 @@
+package minc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/minc-org/minc/pkg/cluster"
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/hostdns"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+)
+
+// DNSHosts resolves the API server and the route hosts to 127.0.0.1 in the
+// hosts file. With Watch it follows the routes until ctx is done.
+func DNSHosts(ctx context.Context, dType *types.DNSHostsType) error {
+	path := dType.HostsFile
+	if path == "" {
+		path = hostdns.DefaultHostsFile()
+	}
+	p, err := runningCluster(ctx, dType.Provider)
+	if err != nil {
+		return err
+	}
+	config, err := p.GetKubeConfig(ctx)
+	if err != nil {
+		return err
+	}
+	update := func(routes []cluster.Route) error {
+		hosts := make([]string, 0, len(routes))
+		for _, r := range routes {
+			hosts = append(hosts, r.Host)
+		}
+		hosts = hostdns.Hosts(hosts)
+		changed, err := hostdns.Update(path, hosts)
+		if err != nil {
+			return err
+		}
+		if changed {
+			log.Info("Updated the hosts file", "file", path, "hosts", len(hosts))
+		}
+		return nil
+	}
+	if !dType.Watch {
+		routes, err := cluster.ListRoutes(ctx, config, "")
+		if err != nil {
+			return err
+		}
+		return update(routes)
+	}
+	log.Info("Watching the routes, press Ctrl-C to stop", "file", path)
+	err = cluster.WatchRoutes(ctx, config, update)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// DNSRemoveHosts removes the entries DNSHosts added to the hosts file
+func DNSRemoveHosts(path string) error {
+	if path == "" {
+		path = hostdns.DefaultHostsFile()
+	}
+	return hostdns.Remove(path)
+}
+
+// DNSServe answers DNS queries for the cluster's domain on listen until ctx
+// is done, for a host resolver configured with DNSConfigure
+func DNSServe(ctx context.Context, listen string) error {
+	s := &hostdns.Server{Domain: constants.HostName}
+	log.Info("Serving DNS", "domain", constants.HostName, "listen", listen)
+	return s.ListenAndServe(ctx, listen)
+}
+
+// DNSConfigure makes the host's resolver use the DNS server on listen for the
+// cluster's domain, it returns the resolver file written
+func DNSConfigure(ctx context.Context, listen string) (string, error) {
+	return hostdns.ConfigureResolver(ctx, constants.HostName, listen)
+}
+
+// DNSUnconfigure reverts DNSConfigure
+func DNSUnconfigure(ctx context.Context) error {
+	return hostdns.UnconfigureResolver(ctx, constants.HostName)
+}
@@ -0,0 +1,76 @@
+package minc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/preflight"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/minc-org/minc/pkg/providers"
+	"github.com/minc-org/minc/pkg/providers/register"
+)
+
+func Doctor(ctx context.Context, dType *types.DoctorType) []preflight.Result {
+	opts := &preflight.Options{
+		Image:     constants.GetUShiftImage(dType.UShiftImage, dType.UShiftVersion),
+		HTTPPort:  dType.HTTPPort,
+		HTTPSPort: dType.HTTPSPort,
+	}
+	opts.ClusterCIDR, opts.ServiceCIDR = clusterCIDRs(dType.ClusterCIDR, dType.ServiceCIDR)
+	p, err := register.Register(ctx, dType.Provider)
+	if err != nil {
+		opts.ProviderErr = err
+	} else {
+		log.Debug("Provider Info", "Provider", p)
+		opts.Provider = p
+		opts.SkipPorts = clusterExists(ctx, p)
+		opts.EngineSubnets = engineSubnets(ctx, p, constants.NetworkName, "")
+	}
+	return preflight.Run(ctx, opts)
+}
+
+// preflightCreate runs the preflight checks before create, reporting warnings
+// to r and failing on any failed check.
+func preflightCreate(ctx context.Context, p providers.Provider, cType *types.CreateType, r progress.Reporter) error {
+	clusterCIDR, serviceCIDR := clusterCIDRs(cType.ClusterCIDR, cType.ServiceCIDR)
+	results := preflight.Run(ctx, &preflight.Options{
+		Provider:      p,
+		Image:         cType.ImageRef(),
+		HTTPPort:      cType.HTTPPort,
+		HTTPSPort:     cType.HTTPSPort,
+		ClusterCIDR:   clusterCIDR,
+		ServiceCIDR:   serviceCIDR,
+		EngineSubnets: engineSubnets(ctx, p, cType.Network, cType.Subnet),
+		SkipPorts:     clusterExists(ctx, p),
+	})
+	for _, res := range results {
+		if res.Status == preflight.Warn {
+			msg := fmt.Sprintf("%s: %s", res.Name, res.Message)
+			if res.Hint != "" {
+				msg += " (" + res.Hint + ")"
+			}
+			r.Warn(msg)
+		}
+	}
+	return preflight.Failed(results)
+}
+
+// clusterCIDRs returns the pod and service networks MicroShift uses
+func clusterCIDRs(clusterCIDR, serviceCIDR string) (string, string) {
+	if clusterCIDR == "" {
+		clusterCIDR = constants.DefaultClusterCIDR
+	}
+	if serviceCIDR == "" {
+		serviceCIDR = constants.DefaultServiceCIDR
+	}
+	return clusterCIDR, serviceCIDR
+}
+
+// clusterExists reports whether the MicroShift container exists, running or not.
+func clusterExists(ctx context.Context, p providers.Provider) bool {
+	out, _ := p.List(ctx)
+	return len(out) > 0
+}
@@ -1,26 +1,32 @@
 package minc
 
 import (
+	"context"
 	"github.com/minc-org/minc/pkg/kubeconfig"
 	"github.com/minc-org/minc/pkg/log"
 	"github.com/minc-org/minc/pkg/providers/register"
 )
 
-func GenerateKubeConfig(provider string) error {
-	p, err := register.Register(provider)
+func GenerateKubeConfig(ctx context.Context, provider string) error {
+	p, err := register.Register(ctx, provider)
 	if err != nil {
 		return err
 	}
 	log.Debug("Provider Info", "Provider", p)
-	if _, err := p.List(); err != nil {
+	if _, err := p.List(ctx); err != nil {
 		return err
 	}
-	config, err := p.GetKubeConfig()
+	config, err := p.GetKubeConfig(ctx)
 	if err != nil {
 		return err
 	}
 	if err := kubeconfig.UpdateKubeConfig(config); err != nil {
 		return err
 	}
-	return nil
+	sans := recordedAPISANs()
+	paths, err := saveSANKubeConfigs(ctx, p, sans)
+	for i, path := range paths {
+		log.Info("kubeconfig generated", "api-san", sans[i], "path", path)
+	}
+	return err
 }
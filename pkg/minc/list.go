@@ -1,15 +1,16 @@
 package minc
 
 import (
+	"context"
 	"github.com/minc-org/minc/pkg/log"
 	"github.com/minc-org/minc/pkg/providers/register"
 )
 
-func List(provider string) ([]byte, error) {
-	p, err := register.Register(provider)
+func List(ctx context.Context, provider string) ([]byte, error) {
+	p, err := register.Register(ctx, provider)
 	if err != nil {
 		return nil, err
 	}
 	log.Debug("Provider Info", "Provider", p)
-	return p.List()
+	return p.List(ctx)
 }
@@ -0,0 +1,40 @@
+package minc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/providers"
+	"github.com/minc-org/minc/pkg/providers/register"
+)
+
+// journalTailLines is the number of microshift journal lines shown when the
+// service fails to come up during create.
+const journalTailLines = 50
+
+func Logs(ctx context.Context, lType *types.LogsType) error {
+	p, err := register.Register(ctx, lType.Provider)
+	if err != nil {
+		return err
+	}
+	log.Debug("Provider Info", "Provider", p)
+	return p.Logs(ctx, os.Stdout, lType)
+}
+
+// dumpMicroShiftJournal writes the tail of the microshift journal to w so a
+// failed service wait comes with some explanation.
+func dumpMicroShiftJournal(ctx context.Context, p providers.Provider, w io.Writer) {
+	fmt.Fprintf(w, "--- last %d lines of the microshift journal ---\n", journalTailLines)
+	lType := &types.LogsType{
+		Component: "microshift",
+		Tail:      journalTailLines,
+	}
+	if err := p.Logs(ctx, w, lType); err != nil {
+		log.Warn("failed to read microshift journal", "err", err)
+	}
+	fmt.Fprintln(w, "--- end of microshift journal, see 'minc logs' for more ---")
+}
@@ -0,0 +1,172 @@
+package minc
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/minc-org/minc/pkg/clusterstate"
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/minc-org/minc/pkg/providers"
+	"github.com/minc-org/minc/pkg/providers/register"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// defaultNetworks are the networks containers join without --network
+var defaultNetworks = map[string]string{
+	"docker": "bridge",
+	"podman": "podman",
+}
+
+// checkNetworkOptions validates the subnet and the static IP in it, the pod
+// and service networks and the API server SANs
+func checkNetworkOptions(cType *types.CreateType) error {
+	for _, san := range cType.APISANs {
+		if _, err := netip.ParseAddr(san); err == nil {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(san); len(errs) > 0 {
+			return fmt.Errorf("invalid --api-san %q, expected an IP or a DNS name: %s", san, strings.Join(errs, ", "))
+		}
+	}
+	for _, c := range []struct{ flag, cidr string }{
+		{"--cluster-cidr", cType.ClusterCIDR},
+		{"--service-cidr", cType.ServiceCIDR},
+	} {
+		if c.cidr == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(c.cidr)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", c.flag, c.cidr, err)
+		}
+		if prefix != prefix.Masked() {
+			return fmt.Errorf("invalid %s %q, did you mean %s?", c.flag, c.cidr, prefix.Masked())
+		}
+	}
+	if cType.Network == "" {
+		if cType.Subnet != "" || cType.IP != "" {
+			return fmt.Errorf("--subnet and --ip need the cluster network, the cluster was created on the default bridge")
+		}
+		return nil
+	}
+	if cType.Subnet == "" {
+		if cType.IP != "" {
+			return fmt.Errorf("--ip needs the subnet it belongs to, set --subnet")
+		}
+		return nil
+	}
+	prefix, err := netip.ParsePrefix(cType.Subnet)
+	if err != nil {
+		return fmt.Errorf("invalid subnet %q: %w", cType.Subnet, err)
+	}
+	if prefix != prefix.Masked() {
+		return fmt.Errorf("invalid subnet %q, did you mean %s?", cType.Subnet, prefix.Masked())
+	}
+	if cType.IP == "" {
+		return nil
+	}
+	ip, err := netip.ParseAddr(cType.IP)
+	if err != nil {
+		return fmt.Errorf("invalid IP %q: %w", cType.IP, err)
+	}
+	if !prefix.Contains(ip) {
+		return fmt.Errorf("the IP %s is not in the subnet %s", ip, prefix)
+	}
+	return nil
+}
+
+// ensureNetwork creates the cluster's network unless it exists, an existing
+// network must have the requested subnet. It returns whether it created it.
+func ensureNetwork(ctx context.Context, p providers.Provider, cType *types.CreateType, r progress.Reporter) (bool, error) {
+	inspect, err := p.InspectNetwork(ctx, cType.Network)
+	if err != nil {
+		r.Update(fmt.Sprintf("creating the network %s", cType.Network))
+		return true, p.CreateNetwork(ctx, cType.Network, cType.Subnet)
+	}
+	if cType.Subnet == "" {
+		return false, nil
+	}
+	subnets, err := providers.NetworkSubnets(inspect)
+	if err != nil {
+		return false, err
+	}
+	if !slices.Contains(subnets, cType.Subnet) {
+		return false, fmt.Errorf("the network %s exists with the subnet %v, not %s: delete it or use its subnet",
+			cType.Network, subnets, cType.Subnet)
+	}
+	return false, nil
+}
+
+// engineSubnets returns the subnets of the engine network the container
+// joins, nil while it does not exist and its subnet is left to the engine
+func engineSubnets(ctx context.Context, p providers.Provider, network, subnet string) []string {
+	if subnet != "" {
+		return []string{subnet}
+	}
+	if network == "" {
+		network = defaultNetworks[p.Name()]
+	}
+	inspect, err := p.InspectNetwork(ctx, network)
+	if err != nil {
+		return nil
+	}
+	subnets, err := providers.NetworkSubnets(inspect)
+	if err != nil {
+		log.Debug("failed to read the network subnets", "network", network, "err", err)
+	}
+	return subnets
+}
+
+// deleteNetwork deletes the cluster's network when minc created it, keeping
+// it while other containers are still connected
+func deleteNetwork(ctx context.Context, p providers.Provider, state *clusterstate.State, r progress.Reporter) {
+	if _, err := p.InspectNetwork(ctx, constants.NetworkName); err != nil {
+		return
+	}
+	if state == nil || !state.NetworkCreated {
+		log.Info(fmt.Sprintf("Keeping the network %s, minc did not create it", constants.NetworkName))
+		return
+	}
+	if err := p.DeleteNetwork(ctx, constants.NetworkName); err != nil {
+		r.Warn(fmt.Sprintf("the network %s is kept, disconnect its containers with 'minc network disconnect' first: %v",
+			constants.NetworkName, err))
+	}
+}
+
+// NetworkConnect attaches a container to the cluster's network, where it
+// reaches the cluster as microshift and pods reach it by its name.
+func NetworkConnect(ctx context.Context, provider, container string) error {
+	p, err := clusterNetwork(ctx, provider)
+	if err != nil {
+		return err
+	}
+	return p.ConnectNetwork(ctx, constants.NetworkName, container)
+}
+
+// NetworkDisconnect detaches a container from the cluster's network.
+func NetworkDisconnect(ctx context.Context, provider, container string) error {
+	p, err := clusterNetwork(ctx, provider)
+	if err != nil {
+		return err
+	}
+	return p.DisconnectNetwork(ctx, constants.NetworkName, container)
+}
+
+// clusterNetwork returns the provider once the cluster's network exists
+func clusterNetwork(ctx context.Context, provider string) (providers.Provider, error) {
+	p, err := register.Register(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Provider Info", "Provider", p)
+	if _, err := p.InspectNetwork(ctx, constants.NetworkName); err != nil {
+		return nil, fmt.Errorf("the cluster network %s does not exist, create the cluster with 'minc create' first", constants.NetworkName)
+	}
+	return p, nil
+}
@@ -0,0 +1,48 @@
+package minc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minc-org/minc/pkg/log"
+)
+
+// rollbackTimeout bounds undoing all recorded steps of a failed create
+const rollbackTimeout = 2 * time.Minute
+
+// rollback records the completed steps of a create, or another multi-step
+// operation, so a failure can undo them in reverse order.
+type rollback struct {
+	// operation names what failed in the log, e.g. "create"
+	operation string
+	steps     []rollbackStep
+}
+
+type rollbackStep struct {
+	name string
+	undo func(ctx context.Context) error
+}
+
+func (r *rollback) add(name string, undo func(ctx context.Context) error) {
+	r.steps = append(r.steps, rollbackStep{name: name, undo: undo})
+}
+
+// run undoes all recorded steps, newest first. It uses its own context since
+// the operation's context may be the reason for the failure. Failing steps are
+// logged and do not stop the remaining ones.
+func (r *rollback) run() {
+	if len(r.steps) == 0 {
+		return
+	}
+	log.Warn(fmt.Sprintf("%s failed, rolling back ...", r.operation))
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		step := r.steps[i]
+		log.Info("Rolling back", "step", step.name)
+		if err := step.undo(ctx); err != nil {
+			log.Error("failed to roll back", "step", step.name, "err", err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package minc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minc-org/minc/pkg/cluster"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/providers"
+)
+
+// Routes returns the routes of the cluster with the URLs they are reached on
+// from the host, through the router ports the container publishes
+func Routes(ctx context.Context, rType *types.RoutesType) ([]types.RouteURL, error) {
+	p, err := runningCluster(ctx, rType.Provider)
+	if err != nil {
+		return nil, err
+	}
+	inspect, err := p.Inspect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ports := map[string]int{}
+	for scheme, port := range map[string]int{"http": 80, "https": 443} {
+		if ports[scheme], err = providers.PublishedPort(inspect, port); err != nil {
+			return nil, err
+		}
+	}
+	config, err := p.GetKubeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := cluster.ListRoutes(ctx, config, rType.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	var urls []types.RouteURL
+	for _, r := range routes {
+		if rType.Name != "" && r.Name != rType.Name {
+			continue
+		}
+		urls = append(urls, types.RouteURL{
+			Namespace: r.Namespace,
+			Name:      r.Name,
+			Host:      r.Host,
+			Service:   r.Service,
+			URL:       routeURL(r, ports),
+		})
+	}
+	return urls, nil
+}
+
+// routeURL is the host side URL of the route, routes with TLS are served on
+// the https port. The port is left out when it is the scheme's default, or
+// the URL is empty when the router port is not published.
+func routeURL(r cluster.Route, ports map[string]int) string {
+	scheme := "http"
+	if r.TLS != "" {
+		scheme = "https"
+	}
+	port := ports[scheme]
+	switch {
+	case port == 0:
+		return ""
+	case (scheme == "http" && port == 80) || (scheme == "https" && port == 443):
+		return fmt.Sprintf("%s://%s%s", scheme, r.Host, r.Path)
+	default:
+		return fmt.Sprintf("%s://%s:%d%s", scheme, r.Host, port, r.Path)
+	}
+}
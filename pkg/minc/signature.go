@@ -0,0 +1,87 @@
+package minc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/providers"
+	"github.com/minc-org/minc/pkg/reference"
+	"github.com/minc-org/minc/pkg/signature"
+)
+
+// checkSignatureOptions validates the signature verification settings of create
+func checkSignatureOptions(cType *types.CreateType, fromSnapshot bool) error {
+	if cType.SignatureKey == "" && cType.SignaturePolicy == "" {
+		if cType.SignatureBundle != "" {
+			return fmt.Errorf("--signature-bundle needs the public key to verify it, set --signature-key")
+		}
+		return nil
+	}
+	if fromSnapshot {
+		log.Warn("Not verifying the image signature, snapshot images are local")
+		cType.SignatureKey, cType.SignatureBundle, cType.SignaturePolicy = "", "", ""
+		return nil
+	}
+	if cType.SignatureKey != "" && cType.SignaturePolicy != "" {
+		return fmt.Errorf("verify the image either with --signature-key or with --signature-policy")
+	}
+	if cType.SignatureKey != "" && cType.SignatureBundle == "" {
+		return fmt.Errorf("%w: --signature-key needs the image signature, download it with 'cosign download signature' and pass it with --signature-bundle",
+			signature.ErrInvalidSignature)
+	}
+	return nil
+}
+
+// pullImage pulls the image, with the signature policy when one is set
+func pullImage(ctx context.Context, p providers.Provider, img string, cType *types.CreateType, w io.Writer) error {
+	if cType.SignaturePolicy == "" {
+		return p.PullImage(ctx, img, w)
+	}
+	err := p.PullImageWithPolicy(ctx, img, cType.SignaturePolicy, w)
+	// podman reports "Source image rejected: ..." for images failing the policy
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "rejected") {
+		return fmt.Errorf("%w: %w", signature.ErrInvalidSignature, err)
+	}
+	return err
+}
+
+// verifySignature verifies the signature of the pulled image and returns the
+// result recorded with the cluster, nil without signature settings
+func verifySignature(ctx context.Context, p providers.Provider, img string, cType *types.CreateType) (*signature.Result, error) {
+	now := time.Now()
+	switch {
+	case cType.SignaturePolicy != "":
+		// the policy was enforced by the pull
+		return &signature.Result{Verified: true, Method: signature.MethodPolicy, Image: img,
+			Policy: cType.SignaturePolicy, Time: &now}, nil
+	case cType.SignatureKey != "":
+		key, err := signature.LoadPublicKey(cType.SignatureKey)
+		if err != nil {
+			return nil, err
+		}
+		bundle, err := signature.LoadBundle(cType.SignatureBundle)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := reference.Parse(img)
+		if err != nil {
+			return nil, err
+		}
+		digests, err := p.ImageDigests(ctx, img)
+		if err != nil {
+			return nil, err
+		}
+		digest, err := signature.VerifyCosign(key, bundle, ref.Repository, digests)
+		if err != nil {
+			return nil, err
+		}
+		return &signature.Result{Verified: true, Method: signature.MethodCosign, Image: img,
+			Digest: digest, Key: cType.SignatureKey, Time: &now}, nil
+	}
+	return nil, nil
+}
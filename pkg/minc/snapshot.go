@@ -0,0 +1,379 @@
+package minc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minc-org/minc/pkg/clusterstate"
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/minc-org/minc/pkg/providers"
+	"github.com/minc-org/minc/pkg/providers/register"
+	"github.com/minc-org/minc/pkg/snapshot"
+)
+
+// SnapshotSave snapshots the cluster as name. A running cluster is stopped
+// for a consistent snapshot and started again afterwards.
+func SnapshotSave(ctx context.Context, provider, name string, r progress.Reporter) (err error) {
+	if err := snapshot.ValidateName(name); err != nil {
+		return err
+	}
+	if snapshot.Exists(name) {
+		return fmt.Errorf("snapshot %s already exists, delete it first with 'minc snapshot delete %s'", name, name)
+	}
+	state, err := clusterstate.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("the cluster settings were not recorded, snapshots need a cluster created with this minc version")
+	}
+	if err != nil {
+		return fmt.Errorf("reading the cluster settings: %w", err)
+	}
+	p, err := register.Register(ctx, provider)
+	if err != nil {
+		return err
+	}
+	log.Debug("Provider Info", "Provider", p)
+	out, _ := p.List(ctx)
+	if len(out) == 0 {
+		return fmt.Errorf("%w: use 'minc create' to create the cluster", providers.ErrNoSuchContainer)
+	}
+	inspect, err := p.Inspect(ctx)
+	if err != nil {
+		return err
+	}
+	mounts, err := providers.VolumeMounts(inspect)
+	if err != nil {
+		return err
+	}
+
+	snap := &snapshot.Snapshot{
+		Name:        name,
+		Created:     time.Now(),
+		Provider:    p.Name(),
+		Image:       snapshot.Image(name),
+		SourceImage: state.Cluster.ImageRef(),
+		Cluster:     state.Cluster,
+	}
+	// a failed save leaves nothing behind
+	rb := &rollback{operation: "Snapshot save"}
+	defer func() {
+		if err != nil {
+			rb.run()
+		}
+	}()
+	rb.add("snapshot files", func(ctx context.Context) error {
+		return snapshot.Remove(name)
+	})
+
+	if strings.Contains(string(out), "running") {
+		err := progress.Run(r, progress.PhaseStop, "Stopping the MicroShift container", func() error {
+			return p.Stop(ctx)
+		})
+		if err != nil {
+			return err
+		}
+		defer restartAfterSnapshot(ctx, p, r)
+	}
+	err = progress.Run(r, progress.PhaseCommit, fmt.Sprintf("Committing the container as %s", snap.Image), func() error {
+		return p.Commit(ctx, snap.Image)
+	})
+	if err != nil {
+		return err
+	}
+	rb.add("snapshot image", func(ctx context.Context) error {
+		return p.RemoveImage(ctx, snap.Image)
+	})
+	if len(mounts) > 0 {
+		err = progress.Run(r, progress.PhaseExportVolumes, "Exporting the container volumes", func() error {
+			volumes, err := exportVolumes(ctx, p, name, mounts, r)
+			snap.Volumes = volumes
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return snapshot.Save(snap)
+}
+
+// restartAfterSnapshot starts the container SnapshotSave stopped, even when
+// the save failed or was interrupted
+func restartAfterSnapshot(ctx context.Context, p providers.Provider, r progress.Reporter) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopTimeout)
+	defer cancel()
+	err := progress.Run(r, progress.PhaseStart, "Starting the MicroShift container", func() error {
+		return p.Start(ctx)
+	})
+	if err != nil {
+		log.Error("failed to start the MicroShift container again, use 'minc create' to start it", "err", err)
+	}
+}
+
+// exportVolumes archives every mounted volume into the snapshot's volumes directory
+func exportVolumes(ctx context.Context, p providers.Provider, name string, mounts []providers.Mount, r progress.Reporter) ([]snapshot.Volume, error) {
+	dir, err := snapshot.VolumesDir(name)
+	if err != nil {
+		return nil, err
+	}
+	var volumes []snapshot.Volume
+	for i, m := range mounts {
+		r.Update(m.Destination)
+		v := snapshot.Volume{Destination: m.Destination, Archive: fmt.Sprintf("%d.tar", i)}
+		if err := exportVolumeTo(ctx, p, m.Name, filepath.Join(dir, v.Archive)); err != nil {
+			return nil, fmt.Errorf("exporting the volume mounted at %s: %w", m.Destination, err)
+		}
+		if info, err := os.Stat(filepath.Join(dir, v.Archive)); err == nil {
+			v.Size = info.Size()
+		}
+		volumes = append(volumes, v)
+	}
+	return volumes, nil
+}
+
+// loadSnapshot loads the snapshot cType is created from and applies its
+// cluster settings to cType, the cluster is recreated exactly as it was.
+func loadSnapshot(ctx context.Context, p providers.Provider, cType *types.CreateType) (*snapshot.Snapshot, error) {
+	snap, err := snapshot.Load(cType.FromSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	if snap.Provider != p.Name() {
+		return nil, fmt.Errorf("snapshot %s was taken with %s, use '--provider %s'", snap.Name, snap.Provider, snap.Provider)
+	}
+	if clusterExists(ctx, p) {
+		return nil, fmt.Errorf("%w: delete the cluster first or use 'minc snapshot restore %s'", providers.ErrNameInUse, snap.Name)
+	}
+	if !p.ImageExists(ctx, snap.Image) {
+		return nil, fmt.Errorf("%w: %s of snapshot %s, delete the snapshot with 'minc snapshot delete %s'",
+			providers.ErrImageNotFound, snap.Image, snap.Name, snap.Name)
+	}
+	applySnapshot(cType, snap)
+	// the snapshot's data is restored into a fresh data volume
+	if cType.PersistentData && p.VolumeExists(ctx, constants.DataVolume) {
+		return nil, fmt.Errorf("the data volume %s exists, remove it with 'minc delete' first", constants.DataVolume)
+	}
+	return snap, nil
+}
+
+// applySnapshot sets the cluster settings recorded in the snapshot and its image
+func applySnapshot(cType *types.CreateType, snap *snapshot.Snapshot) {
+	if c := snap.Cluster; c != nil {
+		cType.UShiftVersion, cType.UShiftImage, cType.UShiftConfig = c.UShiftVersion, c.UShiftImage, c.UShiftConfig
+		cType.HTTPPort, cType.HTTPSPort = c.HTTPPort, c.HTTPSPort
+		cType.DisableOverlayCache, cType.PersistentData = c.DisableOverlayCache, c.PersistentData
+		cType.Network, cType.Subnet, cType.IP = c.Network, c.Subnet, c.IP
+		cType.ClusterCIDR, cType.ServiceCIDR = c.ClusterCIDR, c.ServiceCIDR
+		cType.APISANs, cType.RouterCertSecret = c.APISANs, c.RouterCertSecret
+	}
+	cType.Image = snap.Image
+}
+
+// importVolumes restores the snapshot's archives into the volumes of the
+// container created from it, matching them by mount destination
+func importVolumes(ctx context.Context, p providers.Provider, snap *snapshot.Snapshot, r progress.Reporter) error {
+	inspect, err := p.Inspect(ctx)
+	if err != nil {
+		return err
+	}
+	mounts, err := providers.VolumeMounts(inspect)
+	if err != nil {
+		return err
+	}
+	byDestination := map[string]providers.Mount{}
+	for _, m := range mounts {
+		byDestination[m.Destination] = m
+	}
+	for _, v := range snap.Volumes {
+		m, ok := byDestination[v.Destination]
+		if !ok {
+			return fmt.Errorf("the container has no volume mounted at %s to restore into", v.Destination)
+		}
+		r.Update(v.Destination)
+		archive, err := snap.ArchivePath(v)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(archive)
+		if err != nil {
+			return err
+		}
+		err = p.ImportVolume(ctx, m.Name, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("importing the volume mounted at %s: %w", v.Destination, err)
+		}
+	}
+	return nil
+}
+
+// SnapshotRestore replaces the cluster with one created from the snapshot
+// cType.FromSnapshot. The current data volume is backed up first, a failed
+// restore recreates the current cluster with its data.
+func SnapshotRestore(ctx context.Context, cType *types.CreateType, r progress.Reporter) (err error) {
+	snap, err := snapshot.Load(cType.FromSnapshot)
+	if err != nil {
+		return err
+	}
+	p, err := register.Register(ctx, cType.Provider)
+	if err != nil {
+		return err
+	}
+	log.Debug("Provider Info", "Provider", p)
+	// fail before deleting anything
+	if snap.Provider != p.Name() {
+		return fmt.Errorf("snapshot %s was taken with %s, use '--provider %s'", snap.Name, snap.Provider, snap.Provider)
+	}
+	if !p.ImageExists(ctx, snap.Image) {
+		return fmt.Errorf("%w: %s of snapshot %s", providers.ErrImageNotFound, snap.Image, snap.Name)
+	}
+	restored := *cType
+	applySnapshot(&restored, snap)
+	if err := checkImageRef(restored.ImageRef(), &restored, snap); err != nil {
+		return err
+	}
+	if err := checkNetworkOptions(&restored); err != nil {
+		return err
+	}
+	if !cType.SkipPreflight {
+		// the ports of the current cluster are freed by deleting it
+		err := progress.Run(r, progress.PhasePreflight, "Running preflight checks", func() error {
+			return preflightCreate(ctx, p, &restored, r)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	previous, err := clusterstate.Load()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading the cluster settings: %w", err)
+	}
+
+	rb := &rollback{operation: "Snapshot restore"}
+	defer func() {
+		if err != nil {
+			rb.run()
+		}
+	}()
+	out, _ := p.List(ctx)
+	if p.VolumeExists(ctx, constants.DataVolume) {
+		backup, err := backupCurrentData(ctx, p, strings.Contains(string(out), "running"), r)
+		if err != nil {
+			return err
+		}
+		rb.add("previous cluster", func(ctx context.Context) error {
+			if previous == nil || previous.Cluster == nil {
+				log.Error("the previous cluster settings were not recorded, its data is kept for a manual restore", "backup", backup)
+				return nil
+			}
+			if err := restorePreviousCluster(ctx, p, previous.Cluster, backup); err != nil {
+				log.Error("the previous data is kept for a manual restore", "backup", backup)
+				return err
+			}
+			if err := clusterstate.Save(previous); err != nil {
+				log.Warn("failed to record the cluster settings", "err", err)
+			}
+			log.Warn("Restored the previous cluster, it is starting again")
+			return os.Remove(backup)
+		})
+		defer func() {
+			if err == nil {
+				if rmErr := os.Remove(backup); rmErr != nil {
+					log.Warn("failed to remove the data backup", "backup", backup, "err", rmErr)
+				}
+			}
+		}()
+	}
+	if len(out) > 0 {
+		err := progress.Run(r, progress.PhaseDelete, "Deleting the current MicroShift container", func() error {
+			return p.Delete(ctx)
+		})
+		if err != nil {
+			return err
+		}
+		if err := clusterstate.Remove(); err != nil {
+			r.Warn(fmt.Sprintf("failed to remove the recorded cluster settings: %v", err))
+		}
+	}
+	if p.VolumeExists(ctx, constants.DataVolume) {
+		err := progress.Run(r, progress.PhaseDeleteVolumes, "Deleting the current MicroShift data volume", func() error {
+			return p.DeleteVolume(ctx, constants.DataVolume)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := Create(ctx, cType, r); err != nil {
+		return err
+	}
+	// the network outlived the previous cluster, minc still owns it
+	if previous != nil && previous.NetworkCreated && cType.Network != "" {
+		state, err := clusterstate.Load()
+		if err == nil {
+			state.NetworkCreated = true
+			err = clusterstate.Save(state)
+		}
+		if err != nil {
+			log.Warn("failed to record the cluster settings", "err", err)
+		}
+	}
+	return nil
+}
+
+// backupCurrentData exports the data volume of the current cluster, stopping
+// it first when running, and returns the archive path. The cluster is started
+// again if the export fails.
+func backupCurrentData(ctx context.Context, p providers.Provider, running bool, r progress.Reporter) (string, error) {
+	if running {
+		err := progress.Run(r, progress.PhaseStop, "Stopping the MicroShift container", func() error {
+			return p.Stop(ctx)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	backup, err := dataBackupPath("restore", "current")
+	if err == nil {
+		err = progress.Run(r, progress.PhaseBackupData, fmt.Sprintf("Backing up the %s volume", constants.DataVolume), func() error {
+			return exportVolumeTo(ctx, p, constants.DataVolume, backup)
+		})
+		if err != nil {
+			os.Remove(backup)
+		}
+	}
+	if err != nil {
+		if running {
+			restartPrevious(p)
+		}
+		return "", err
+	}
+	return backup, nil
+}
+
+// SnapshotList returns the recorded snapshots, oldest first
+func SnapshotList() ([]*snapshot.Snapshot, error) {
+	return snapshot.List()
+}
+
+// SnapshotDelete removes the snapshot's image, volume archives and metadata
+func SnapshotDelete(ctx context.Context, name string) error {
+	snap, err := snapshot.Load(name)
+	if err != nil {
+		return err
+	}
+	p, err := register.Register(ctx, snap.Provider)
+	if err != nil {
+		return err
+	}
+	log.Debug("Provider Info", "Provider", p)
+	if err := p.RemoveImage(ctx, snap.Image); err != nil && !errors.Is(err, providers.ErrImageNotFound) {
+		return err
+	}
+	return snapshot.Remove(name)
+}
@@ -1,23 +1,32 @@
 package minc
 
 import (
+	"context"
 	"github.com/minc-org/minc/pkg/cluster"
+	"github.com/minc-org/minc/pkg/clusterstate"
 	"github.com/minc-org/minc/pkg/minc/types"
 	"github.com/minc-org/minc/pkg/providers/register"
+	"github.com/minc-org/minc/pkg/signature"
 	"strings"
 )
 
-func Status(provider string) *types.StatusType {
+func Status(ctx context.Context, provider string) *types.StatusType {
 	status := types.StatusType{
 		Container: "stopped",
 		APIServer: "stopped",
 	}
-	p, err := register.Register(provider)
+	if state, err := clusterstate.Load(); err == nil {
+		status.Signature = state.Signature
+		if status.Signature == nil && state.Cluster != nil {
+			status.Signature = &signature.Result{Verified: false, Image: state.Cluster.ImageRef()}
+		}
+	}
+	p, err := register.Register(ctx, provider)
 	if err != nil {
 		status.Error = err.Error()
 		return &status
 	}
-	out, err := p.List()
+	out, err := p.List(ctx)
 	if err != nil {
 		status.Error = err.Error()
 		return &status
@@ -25,12 +34,12 @@ func Status(provider string) *types.StatusType {
 	if strings.Contains(string(out), "running") {
 		status.Container = "running"
 	}
-	config, err := p.GetKubeConfig()
+	config, err := p.GetKubeConfig(ctx)
 	if err != nil {
 		status.Error = err.Error()
 		return &status
 	}
-	if err := cluster.GetPodStatus(config); err != nil {
+	if err := cluster.GetPodStatus(ctx, config); err != nil {
 		status.Error = err.Error()
 		return &status
 	}
@@ -1,17 +1,188 @@
 package types
 
+import (
+	"time"
+
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/signature"
+)
+
+// CreateType holds the settings of a create, the fields with a JSON name
+// describe the cluster and are recorded with it (see clusterstate)
 type CreateType struct {
-	Provider            string
-	UShiftVersion       string
-	UShiftImage         string
-	UShiftConfig        string
-	HTTPSPort           int
-	HTTPPort            int
-	DisableOverlayCache bool
+	Provider      string `json:"provider"`
+	UShiftVersion string `json:"microshiftVersion"`
+	UShiftImage   string `json:"microshiftImage,omitempty"`
+	// Image is a complete image reference used instead of UShiftImage and
+	// UShiftVersion, e.g. the image of a snapshot
+	Image               string `json:"image,omitempty"`
+	UShiftConfig        string `json:"microshiftConfig,omitempty"`
+	HTTPSPort           int    `json:"httpsPort"`
+	HTTPPort            int    `json:"httpPort"`
+	DisableOverlayCache bool   `json:"disableOverlayCache"`
+	// PersistentData keeps MicroShift's data directory on constants.DataVolume
+	// so it survives recreating the container
+	PersistentData bool `json:"persistentData,omitempty"`
+	// Network is the network the container joins, the engine's default bridge
+	// when empty. Subnet is the network's subnet and IP the container's
+	// static address in it, both optional.
+	Network string `json:"network,omitempty"`
+	Subnet  string `json:"subnet,omitempty"`
+	IP      string `json:"ip,omitempty"`
+	// ClusterCIDR and ServiceCIDR replace MicroShift's default pod and
+	// service networks when set
+	ClusterCIDR string `json:"clusterCIDR,omitempty"`
+	ServiceCIDR string `json:"serviceCIDR,omitempty"`
+	// APISANs are extra names and IPs the API server certificate is valid
+	// for, the API server is then published on all host interfaces
+	APISANs []string `json:"apiSANs,omitempty"`
+	// RouterCertSecret is the secret of the router's default certificate,
+	// recorded by 'minc certs trust'
+	RouterCertSecret string `json:"routerCertSecret,omitempty"`
+	// VerifyDigest is the digest the pulled image must have, e.g. sha256:<hex>
+	VerifyDigest string `json:"verifyDigest,omitempty"`
+	// SignatureKey and SignatureBundle verify the image's cosign signature
+	// offline, SignaturePolicy pulls it with a containers-policy.json(5) file
+	SignatureKey    string `json:"-"`
+	SignatureBundle string `json:"-"`
+	SignaturePolicy string `json:"-"`
+	// FromSnapshot is the snapshot the cluster is created from
+	FromSnapshot  string `json:"fromSnapshot,omitempty"`
+	SkipPreflight bool   `json:"-"`
+	// KeepOnFailure leaves a partially created cluster in place for debugging
+	KeepOnFailure bool `json:"-"`
+	// ServiceWaitTimeout and ServiceWaitInterval control polling the microshift unit
+	ServiceWaitTimeout  time.Duration `json:"-"`
+	ServiceWaitInterval time.Duration `json:"-"`
+}
+
+// ImageRef returns the MicroShift image the cluster runs
+func (c *CreateType) ImageRef() string {
+	if c.Image != "" {
+		return c.Image
+	}
+	return constants.GetUShiftImage(c.UShiftImage, c.UShiftVersion)
+}
+
+// BackupType holds the options of backup and restore
+type BackupType struct {
+	Provider string
+	// File is the .tar.gz archive written by backup and read by restore
+	File                string
+	ServiceWaitTimeout  time.Duration
+	ServiceWaitInterval time.Duration
+}
+
+type UpgradeType struct {
+	Provider      string
+	UShiftVersion string
+	// UShiftImage replaces the cluster's image repository when set
+	UShiftImage string
+	// Force upgrades across version skews MicroShift does not support
+	Force bool
+	// VerifyDigest is the digest the new image must have
+	VerifyDigest string
+	// SignatureBundle is the cosign signature of the new image, needed when
+	// the cluster was verified with a signature key
+	SignatureBundle string
+	// ReadyTimeout bounds how long the upgraded cluster may take to get
+	// ready before the previous version and data are restored
+	ReadyTimeout        time.Duration
+	ServiceWaitTimeout  time.Duration
+	ServiceWaitInterval time.Duration
 }
 
 type StatusType struct {
 	Container string `json:"container"`
 	APIServer string `json:"apiserver"`
-	Error     string `json:"error,omitempty"`
+	// Signature is the signature verification of the image, recorded by create
+	Signature *signature.Result `json:"signature,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+type VersionsType struct {
+	Provider string
+	// UShiftImage is the image repository to list instead of the default one
+	UShiftImage string
+	// PlainHTTP queries the registry over http, for local registries
+	PlainHTTP bool
+}
+
+// ImageVersion is a MicroShift version available in the image repository
+type ImageVersion struct {
+	Version string `json:"version"`
+	Image   string `json:"image"`
+	Default bool   `json:"default"`
+	Local   bool   `json:"local"`
+}
+
+type DeleteType struct {
+	Provider string
+	// KeepData keeps the persistent data volume for a later create
+	KeepData bool
+	// Purge also removes the container storage volume and every other volume
+	// the container mounts
+	Purge bool
+}
+
+type LogsType struct {
+	Provider string
+	// Component is one of microshift, crio or container
+	Component string
+	Follow    bool
+	Since     string
+	Tail      int
+}
+
+type DiagnoseType struct {
+	Provider string
+	// Output is the path of the .tar.gz bundle to write
+	Output string
+	// Config is the effective minc configuration
+	Config map[string]interface{}
+}
+
+type DoctorType struct {
+	Provider      string
+	UShiftVersion string
+	UShiftImage   string
+	HTTPSPort     int
+	HTTPPort      int
+	ClusterCIDR   string
+	ServiceCIDR   string
+}
+
+type DNSHostsType struct {
+	Provider string
+	// HostsFile is the hosts file to manage (default: the host's)
+	HostsFile string
+	// Watch keeps the hosts file in sync with the routes until cancelled
+	Watch bool
+}
+
+type RoutesType struct {
+	Provider string
+	// Namespace lists the routes of one namespace, all when empty
+	Namespace string
+	// Name lists only the route with this name
+	Name string
+}
+
+// RouteURL is an OpenShift route and the URL it is reached on from the host
+type RouteURL struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	Service   string `json:"service"`
+	URL       string `json:"url"`
+}
+
+// CertsType holds the options of the certs commands restarting MicroShift
+type CertsType struct {
+	Provider string
+	// All rotates the CAs and every certificate they signed, not only the
+	// expiring certificates
+	All                 bool
+	ServiceWaitTimeout  time.Duration
+	ServiceWaitInterval time.Duration
 }
@@ -0,0 +1,287 @@
+package minc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minc-org/minc/pkg/cluster"
+	"github.com/minc-org/minc/pkg/clusterstate"
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/progress"
+	"github.com/minc-org/minc/pkg/providers"
+	"github.com/minc-org/minc/pkg/providers/register"
+	"github.com/minc-org/minc/pkg/reference"
+	"github.com/minc-org/minc/pkg/retry"
+	"github.com/minc-org/minc/pkg/signature"
+	"github.com/minc-org/minc/pkg/ushiftversion"
+)
+
+// DefaultUpgradeReadyTimeout bounds how long the upgraded cluster may take to
+// get ready, migrating the data makes its first start slower than a create
+const DefaultUpgradeReadyTimeout = 15 * time.Minute
+
+// upgradePodsInterval is the delay between checks of the upgraded cluster's pods
+const upgradePodsInterval = 5 * time.Second
+
+// Upgrade recreates the MicroShift container on another version, keeping its
+// data volume. MicroShift migrates its data when the new version starts. The
+// data volume is backed up first, a failed upgrade restores the previous
+// container and data. The new image is verified with the signature key or
+// policy the cluster was created with.
+func Upgrade(ctx context.Context, uType *types.UpgradeType, r progress.Reporter) (err error) {
+	state, err := clusterstate.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("the cluster settings were not recorded, upgrades need a cluster created with this minc version")
+	}
+	if err != nil {
+		return fmt.Errorf("reading the cluster settings: %w", err)
+	}
+	from := state.Cluster
+	if !from.PersistentData {
+		return fmt.Errorf("the cluster keeps its data in the container, recreate it with 'minc create --persistent-data' to upgrade it in place")
+	}
+	if uType.Provider != from.Provider {
+		return fmt.Errorf("the cluster was created with %s, use '--provider %s'", from.Provider, from.Provider)
+	}
+	to := *from
+	to.UShiftVersion, to.Image, to.FromSnapshot, to.VerifyDigest = uType.UShiftVersion, "", "", ""
+	if uType.UShiftImage != "" {
+		to.UShiftImage = uType.UShiftImage
+	} else if ref, err := reference.Parse(from.UShiftImage); err == nil && ref.Pinned() {
+		return fmt.Errorf("the cluster runs the pinned image %s, pass the image to upgrade to with --microshift-image", from.UShiftImage)
+	}
+	to.ServiceWaitTimeout, to.ServiceWaitInterval = uType.ServiceWaitTimeout, uType.ServiceWaitInterval
+	// the new image is verified like the cluster's, with the recorded key or policy
+	to.VerifyDigest, to.SignatureBundle = uType.VerifyDigest, uType.SignatureBundle
+	if state.Signature != nil {
+		switch state.Signature.Method {
+		case signature.MethodPolicy:
+			to.SignaturePolicy = state.Signature.Policy
+		case signature.MethodCosign:
+			to.SignatureKey = state.Signature.Key
+		}
+	}
+	img := to.ImageRef()
+	if err := checkImageRef(img, &to, nil); err != nil {
+		return err
+	}
+	if err := checkSignatureOptions(&to, false); err != nil {
+		return err
+	}
+	if err := checkUpgrade(from.UShiftVersion, to.UShiftVersion); err != nil {
+		if !uType.Force {
+			return err
+		}
+		log.Warn("Upgrading despite the unsupported version skew", "err", err)
+	}
+
+	p, err := register.Register(ctx, uType.Provider)
+	if err != nil {
+		return err
+	}
+	log.Debug("Provider Info", "Provider", p)
+	out, _ := p.List(ctx)
+	if len(out) == 0 {
+		return fmt.Errorf("%w: use 'minc create' to create the cluster", providers.ErrNoSuchContainer)
+	}
+	err = progress.Run(r, progress.PhasePull, fmt.Sprintf("Ensuring cluster image (%s)", img), func() error {
+		if err := pullImage(ctx, p, img, &to, progress.Writer(r)); err != nil {
+			return err
+		}
+		if to.VerifyDigest == "" {
+			return nil
+		}
+		r.Update(fmt.Sprintf("verifying the image digest is %s", to.VerifyDigest))
+		return verifyDigest(ctx, p, img, to.VerifyDigest)
+	})
+	if err != nil {
+		return err
+	}
+	var verification *signature.Result
+	if to.SignatureKey != "" || to.SignaturePolicy != "" {
+		err = progress.Run(r, progress.PhaseVerify, "Verifying the image signature", func() error {
+			verification, err = verifySignature(ctx, p, img, &to)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	running := strings.Contains(string(out), "running")
+	if running {
+		err := progress.Run(r, progress.PhaseStop, "Stopping the MicroShift container", func() error {
+			return p.Stop(ctx)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	backup, err := dataBackupPath("upgrade", from.UShiftVersion)
+	if err == nil {
+		err = progress.Run(r, progress.PhaseBackupData, fmt.Sprintf("Backing up the %s volume", constants.DataVolume), func() error {
+			return exportVolumeTo(ctx, p, constants.DataVolume, backup)
+		})
+		if err != nil {
+			os.Remove(backup)
+		}
+	}
+	if err != nil {
+		// nothing changed yet, the cluster keeps running its version
+		if running {
+			restartPrevious(p)
+		}
+		return err
+	}
+
+	rb := &rollback{operation: "Upgrade"}
+	defer func() {
+		if err != nil {
+			rb.run()
+		}
+	}()
+	rb.add("previous cluster", func(ctx context.Context) error {
+		if err := restorePreviousCluster(ctx, p, from, backup); err != nil {
+			log.Error("the previous data is kept for a manual restore", "backup", backup)
+			return err
+		}
+		log.Warn(fmt.Sprintf("Restored MicroShift %s, it is starting again", from.UShiftVersion))
+		return os.Remove(backup)
+	})
+	err = progress.Run(r, progress.PhaseDelete, "Deleting the previous MicroShift container", func() error {
+		return p.Delete(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	err = progress.Run(r, progress.PhaseCreate, fmt.Sprintf("Creating the MicroShift %s container", to.UShiftVersion), func() error {
+		return p.Create(ctx, &to)
+	})
+	if err != nil {
+		return err
+	}
+	err = progress.Run(r, progress.PhaseStart, "Starting the MicroShift container", func() error {
+		return p.Start(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	// the readiness budget replaces the service wait timeout, a slow data
+	// migration must not trigger the rollback to the backup
+	ready := to
+	ready.ServiceWaitTimeout = max(uType.ReadyTimeout, to.ServiceWaitTimeout)
+	deadline := time.Now().Add(ready.ServiceWaitTimeout)
+	if err := waitForService(ctx, p, &ready, r); err != nil {
+		return err
+	}
+	config, err := fetchKubeConfig(ctx, p, from.APISANs, r)
+	if err != nil {
+		return err
+	}
+	err = progress.Run(r, progress.PhaseWaitPods, "Waiting for pods to be ready", func() error {
+		return cluster.WaitForPods(ctx, config, retry.Backoff{
+			Strategy:     retry.Constant,
+			InitialDelay: upgradePodsInterval,
+			// at least one check once the service used up the budget
+			MaxElapsed: max(time.Until(deadline), time.Nanosecond),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := clusterstate.Save(&clusterstate.State{Created: state.Created, Cluster: &to, Signature: verification, NetworkCreated: state.NetworkCreated}); err != nil {
+		log.Warn("failed to record the cluster settings", "err", err)
+	}
+	if err := os.Remove(backup); err != nil {
+		log.Warn("failed to remove the data backup", "backup", backup, "err", err)
+	}
+	return nil
+}
+
+// restartPrevious starts the container Upgrade or SnapshotRestore stopped
+// before failing ahead of any change. It uses its own context since the upgrade context may be done.
+func restartPrevious(p providers.Provider) {
+	log.Warn("Starting the MicroShift container again ...")
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	if err := p.Start(ctx); err != nil {
+		log.Error("failed to start the MicroShift container", "err", err)
+	}
+}
+
+// checkUpgrade validates MicroShift supports upgrading its data between the versions
+func checkUpgrade(from, to string) error {
+	fromVersion, err := ushiftversion.Parse(from)
+	if err != nil {
+		return err
+	}
+	toVersion, err := ushiftversion.Parse(to)
+	if err != nil {
+		return err
+	}
+	return ushiftversion.CheckUpgrade(fromVersion, toVersion)
+}
+
+// dataBackupPath returns where the data volume is backed up during the
+// operation, e.g. an upgrade, label tells backups of one operation apart
+func dataBackupPath(operation, label string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "minc", operation)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%s-%s.tar", constants.DataVolume, label, time.Now().Format("20060102-150405"))
+	return filepath.Join(dir, name), nil
+}
+
+// exportVolumeTo writes a tar archive of the volume to the file path
+func exportVolumeTo(ctx context.Context, p providers.Provider, volume, path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = p.ExportVolume(ctx, volume, f)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+// restorePreviousCluster recreates the container of a failed upgrade on the
+// previous version, with the data volume restored from backup
+func restorePreviousCluster(ctx context.Context, p providers.Provider, from *types.CreateType, backup string) error {
+	if clusterExists(ctx, p) {
+		if err := p.Delete(ctx); err != nil {
+			return err
+		}
+	}
+	// the new version may have migrated the data already
+	if p.VolumeExists(ctx, constants.DataVolume) {
+		if err := p.DeleteVolume(ctx, constants.DataVolume); err != nil {
+			return err
+		}
+	}
+	if err := p.Create(ctx, from); err != nil {
+		return err
+	}
+	f, err := os.Open(backup)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := p.ImportVolume(ctx, constants.DataVolume, f); err != nil {
+		return err
+	}
+	return p.Start(ctx)
+}
@@ -0,0 +1,85 @@
+package minc
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/providers/register"
+	"github.com/minc-org/minc/pkg/reference"
+	"github.com/minc-org/minc/pkg/registry"
+	"github.com/minc-org/minc/pkg/ushiftversion"
+)
+
+// Versions lists the MicroShift versions of the image repository built for
+// this architecture, newest first, marking the default version and the images
+// already pulled.
+func Versions(ctx context.Context, vType *types.VersionsType) ([]types.ImageVersion, error) {
+	repo := constants.GetImageRegistry()
+	if vType.UShiftImage != "" {
+		ref, err := reference.Parse(vType.UShiftImage)
+		if err != nil {
+			return nil, err
+		}
+		repo = ref.Repository
+	}
+	tags, err := registry.New(vType.PlainHTTP).Tags(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	versions := archVersions(repo, tags, runtime.GOARCH)
+
+	p, err := register.Register(ctx, vType.Provider)
+	if err != nil {
+		log.Warn("Can not check the local images", "err", err)
+		return versions, nil
+	}
+	log.Debug("Provider Info", "Provider", p)
+	for i := range versions {
+		versions[i].Local = p.ImageExists(ctx, versions[i].Image)
+	}
+	return versions, nil
+}
+
+// archVersions returns the versions of the tags built for arch, newest first
+func archVersions(repo string, tags []string, arch string) []types.ImageVersion {
+	suffix := "-" + arch
+	var versions []types.ImageVersion
+	for _, tag := range tags {
+		version, found := strings.CutSuffix(tag, suffix)
+		if !found || version == "" {
+			continue
+		}
+		versions = append(versions, types.ImageVersion{
+			Version: version,
+			Image:   constants.GetUShiftImage(repo, version),
+			Default: version == constants.UShiftVersion,
+		})
+	}
+	sortVersions(versions)
+	return versions
+}
+
+// sortVersions sorts newest first, tags which are not MicroShift versions last
+func sortVersions(versions []types.ImageVersion) {
+	parsed := map[string]*ushiftversion.Version{}
+	for _, v := range versions {
+		if pv, err := ushiftversion.Parse(v.Version); err == nil {
+			parsed[v.Version] = pv
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, b := parsed[versions[i].Version], parsed[versions[j].Version]
+		switch {
+		case a != nil && b != nil:
+			return a.Compare(b) > 0
+		case a != nil || b != nil:
+			return a != nil
+		}
+		return versions[i].Version > versions[j].Version
+	})
+}
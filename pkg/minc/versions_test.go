@@ -0,0 +1,48 @@
+package minc
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/minc-org/minc/pkg/constants"
+)
+
+func TestArchVersions(t *testing.T) {
+	arch, other := runtime.GOARCH, "arm64"
+	if arch == other {
+		other = "amd64"
+	}
+	tags := []string{
+		"4.18.0-okd-scos.5-" + arch,
+		"4.19.0-okd-scos.9-" + arch,
+		"latest",
+		"4.20.0-okd-scos.1-" + other,
+		"nightly-" + arch,
+		"4.19.0-okd-scos.17-" + arch,
+		"-" + arch,
+		"4.19.0-okd-scos.10-" + arch,
+		"4.19.0-okd-scos.17-" + other,
+	}
+	versions := archVersions("quay.io/minc-org/minc", tags, arch)
+	var got []string
+	for _, v := range versions {
+		got = append(got, v.Version)
+		if want := "quay.io/minc-org/minc:" + v.Version + "-" + runtime.GOARCH; v.Image != want {
+			t.Errorf("image of %s is %s, want %s", v.Version, v.Image, want)
+		}
+		if v.Default != (v.Version == constants.UShiftVersion) {
+			t.Errorf("%s marked default %v", v.Version, v.Default)
+		}
+	}
+	want := []string{
+		"4.19.0-okd-scos.17",
+		"4.19.0-okd-scos.10",
+		"4.19.0-okd-scos.9",
+		"4.18.0-okd-scos.5",
+		"nightly",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,202 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+
+	"github.com/minc-org/minc/pkg/exec"
+	"github.com/minc-org/minc/pkg/providers"
+)
+
+const (
+	gib = 1 << 30
+	// MicroShift needs 2GiB to start, a few workloads on top need more
+	minMemory         = 2 * gib
+	recommendedMemory = 4 * gib
+	minDisk           = 3 * gib
+	recommendedDisk   = 10 * gib
+	apiServerPort     = 6443
+)
+
+func checkCGroupV2(info *providers.ProviderInfo) Result {
+	if !info.CGroupV2 {
+		return Result{
+			Name:    "cgroup v2",
+			Status:  Fail,
+			Message: "the container engine uses cgroup v1",
+			Hint:    "boot with systemd.unified_cgroup_hierarchy=1, on WSL see https://github.com/spurin/wsl-cgroupsv2",
+		}
+	}
+	return Result{Name: "cgroup v2", Status: Pass, Message: "cgroup v2 is enabled"}
+}
+
+func checkRootMode(opts *Options, info *providers.ProviderInfo) Result {
+	name := opts.Provider.Name()
+	if !info.Rootless {
+		return Result{Name: "rootful mode", Status: Pass, Message: fmt.Sprintf("%s runs rootful", name)}
+	}
+	// rootless podman is only used when --allow-rootless is set, see podman.New
+	if name == "podman" {
+		return Result{
+			Name:    "rootful mode",
+			Status:  Warn,
+			Message: "using rootless mode, which is experimental",
+			Hint:    "see the Rootless Mode section of the README for the required host setup",
+		}
+	}
+	return Result{
+		Name:    "rootful mode",
+		Status:  Fail,
+		Message: fmt.Sprintf("%s runs rootless", name),
+		Hint:    "run the engine in rootful mode or use podman with --allow-rootless",
+	}
+}
+
+// checkSudo verifies sudo is usable when minc runs rootful podman through it.
+func checkSudo(ctx context.Context, opts *Options, info *providers.ProviderInfo) []Result {
+	if runtime.GOOS != "linux" || opts.Provider.Name() != "podman" || info.Rootless || os.Geteuid() == 0 {
+		return nil
+	}
+	err := exec.CommandContext(ctx, "sudo", "-n", "true").Run()
+	// sudo exits with an error code when it needs a password, it did not run
+	// at all when it is not installed
+	var exitErr interface{ ExitCode() int }
+	if err != nil && !errors.As(err, &exitErr) {
+		return []Result{{
+			Name:    "sudo",
+			Status:  Fail,
+			Message: "sudo is not installed",
+			Hint:    "install sudo or use --allow-rootless",
+		}}
+	}
+	if err != nil {
+		return []Result{{
+			Name:    "sudo",
+			Status:  Warn,
+			Message: "sudo requires a password",
+			Hint:    "minc will prompt for your password when it runs podman",
+		}}
+	}
+	return []Result{{Name: "sudo", Status: Pass, Message: "sudo is available"}}
+}
+
+func checkMemory(info *providers.ProviderInfo) Result {
+	mem := fmt.Sprintf("%.1f GiB memory available to the engine", float64(info.MemTotal)/gib)
+	switch {
+	case info.MemTotal == 0:
+		return Result{Name: "memory", Status: Warn, Message: "unable to determine the engine's memory"}
+	case info.MemTotal < minMemory:
+		return Result{
+			Name:    "memory",
+			Status:  Fail,
+			Message: mem,
+			Hint:    "MicroShift needs at least 2 GiB, increase the memory of the host or the engine's VM",
+		}
+	case info.MemTotal < recommendedMemory:
+		return Result{
+			Name:    "memory",
+			Status:  Warn,
+			Message: mem,
+			Hint:    "at least 4 GiB is recommended to run workloads",
+		}
+	}
+	return Result{Name: "memory", Status: Pass, Message: mem}
+}
+
+// errVMStorage is returned by freeDisk when the engine's storage is not on this host
+var errVMStorage = errors.New("the engine's storage lives in its VM")
+
+func checkDisk(info *providers.ProviderInfo) Result {
+	free, err := freeDisk(info.StorageRoot)
+	if errors.Is(err, errVMStorage) {
+		return Result{Name: "disk", Status: Pass, Message: "not checked, " + err.Error()}
+	}
+	if err != nil {
+		return Result{Name: "disk", Status: Warn, Message: fmt.Sprintf("unable to determine free disk space: %v", err)}
+	}
+	disk := fmt.Sprintf("%.1f GiB free in %s", float64(free)/gib, info.StorageRoot)
+	switch {
+	case free < minDisk:
+		return Result{
+			Name:    "disk",
+			Status:  Fail,
+			Message: disk,
+			Hint:    "free up space, e.g. with 'podman system prune' or 'docker system prune'",
+		}
+	case free < recommendedDisk:
+		return Result{
+			Name:    "disk",
+			Status:  Warn,
+			Message: disk,
+			Hint:    "at least 10 GiB is recommended for the MicroShift and workload images",
+		}
+	}
+	return Result{Name: "disk", Status: Pass, Message: disk}
+}
+
+func checkImage(ctx context.Context, opts *Options) Result {
+	if opts.Image == "" {
+		return Result{Name: "image", Status: Pass, Message: "no image to check"}
+	}
+	if opts.Provider.ImageExists(ctx, opts.Image) {
+		return Result{Name: "image", Status: Pass, Message: fmt.Sprintf("%s is present", opts.Image)}
+	}
+	return Result{
+		Name:    "image",
+		Status:  Warn,
+		Message: fmt.Sprintf("%s is not present locally and will be pulled", opts.Image),
+		Hint:    "make sure the registry is reachable or pull the image beforehand",
+	}
+}
+
+func checkPorts(opts *Options) []Result {
+	ports := []struct {
+		name string
+		port int
+		flag string
+	}{
+		{"http port", opts.HTTPPort, "--http-port"},
+		{"https port", opts.HTTPSPort, "--https-port"},
+		{"api server port", apiServerPort, ""},
+	}
+	var results []Result
+	for _, p := range ports {
+		results = append(results, checkPort(p.name, p.port, p.flag))
+	}
+	return results
+}
+
+func checkPort(name string, port int, flag string) Result {
+	// ports below 1024 are bound on all interfaces, see providers.CreateOptions
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	if port < 1024 {
+		addr = fmt.Sprintf(":%d", port)
+	}
+	l, err := net.Listen("tcp", addr)
+	if err == nil {
+		_ = l.Close()
+		return Result{Name: name, Status: Pass, Message: fmt.Sprintf("port %d is free", port)}
+	}
+	if errors.Is(err, os.ErrPermission) {
+		return Result{
+			Name:    name,
+			Status:  Warn,
+			Message: fmt.Sprintf("unable to check port %d: %v", port, err),
+			Hint:    "rootless engines need net.ipv4.ip_unprivileged_port_start lowered to bind ports below 1024",
+		}
+	}
+	hint := "stop the process using the port"
+	if flag != "" {
+		hint += fmt.Sprintf(" or choose another one with %s", flag)
+	}
+	return Result{
+		Name:    name,
+		Status:  Fail,
+		Message: fmt.Sprintf("port %d is in use", port),
+		Hint:    hint,
+	}
+}
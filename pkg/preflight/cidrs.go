@@ -0,0 +1,76 @@
+package preflight
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Route is a host route, Iface is the interface it goes through
+type Route struct {
+	Prefix netip.Prefix
+	Iface  string
+}
+
+// errNoHostRoutes is returned by hostRoutes where the host routes can not be read
+var errNoHostRoutes = errors.New("host routes are only checked on Linux")
+
+// checkCIDRs checks MicroShift's pod and service networks do not overlap each
+// other, a host route, e.g. of a VPN, or the engine networks of the container.
+func checkCIDRs(opts *Options) []Result {
+	if opts.ClusterCIDR == "" && opts.ServiceCIDR == "" {
+		return nil
+	}
+	routes, routesErr := hostRoutes()
+	networks := []struct {
+		name, cidr, flag string
+	}{
+		{"pod network", opts.ClusterCIDR, "--cluster-cidr"},
+		{"service network", opts.ServiceCIDR, "--service-cidr"},
+	}
+	var results []Result
+	for i, n := range networks {
+		prefix, err := netip.ParsePrefix(n.cidr)
+		if err != nil {
+			results = append(results, Result{
+				Name:    n.name,
+				Status:  Fail,
+				Message: fmt.Sprintf("invalid CIDR %q: %v", n.cidr, err),
+				Hint:    fmt.Sprintf("set a CIDR such as 10.42.0.0/16 with %s", n.flag),
+			})
+			continue
+		}
+		var conflicts []string
+		other := networks[1-i]
+		if o, err := netip.ParsePrefix(other.cidr); err == nil && o.Overlaps(prefix) {
+			conflicts = append(conflicts, fmt.Sprintf("the %s %s", other.name, o))
+		}
+		for _, r := range routes {
+			// the default route overlaps everything
+			if r.Prefix.Bits() > 0 && r.Prefix.Overlaps(prefix) {
+				conflicts = append(conflicts, fmt.Sprintf("the host route %s via %s", r.Prefix, r.Iface))
+			}
+		}
+		for _, s := range opts.EngineSubnets {
+			if es, err := netip.ParsePrefix(s); err == nil && es.Overlaps(prefix) {
+				conflicts = append(conflicts, fmt.Sprintf("the engine network %s", es))
+			}
+		}
+		if len(conflicts) > 0 {
+			results = append(results, Result{
+				Name:    n.name,
+				Status:  Fail,
+				Message: fmt.Sprintf("%s overlaps %s", prefix, strings.Join(conflicts, ", ")),
+				Hint:    fmt.Sprintf("choose a free range with %s", n.flag),
+			})
+			continue
+		}
+		msg := fmt.Sprintf("%s does not overlap the host routes or engine networks", prefix)
+		if routesErr != nil {
+			msg = fmt.Sprintf("%s does not overlap the engine networks, %v", prefix, routesErr)
+		}
+		results = append(results, Result{Name: n.name, Status: Pass, Message: msg})
+	}
+	return results
+}
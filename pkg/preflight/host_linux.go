@@ -0,0 +1,167 @@
+package preflight
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// delegatedControllers are the cgroup controllers rootless MicroShift needs
+// delegated to the user session
+var delegatedControllers = []string{"cpuset", "cpu", "io", "memory", "pids"}
+
+// requiredModules are the kernel modules the engine can not load for a rootless container
+var requiredModules = []string{"ip_tables"}
+
+func checkHost(rootless bool) []Result {
+	results := []Result{checkSELinux()}
+	if rootless {
+		results = append(results, checkDelegation())
+		results = append(results, checkModules()...)
+	}
+	return results
+}
+
+func checkDelegation() Result {
+	uid := os.Getuid()
+	path := fmt.Sprintf("/sys/fs/cgroup/user.slice/user-%d.slice/user@%d.service/cgroup.controllers", uid, uid)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{
+			Name:    "cgroup delegation",
+			Status:  Warn,
+			Message: fmt.Sprintf("unable to read delegated controllers: %v", err),
+		}
+	}
+	available := map[string]bool{}
+	for _, c := range strings.Fields(string(data)) {
+		available[c] = true
+	}
+	var missing []string
+	for _, c := range delegatedControllers {
+		if !available[c] {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		return Result{
+			Name:    "cgroup delegation",
+			Status:  Fail,
+			Message: fmt.Sprintf("controllers not delegated to the user session: %s", strings.Join(missing, " ")),
+			Hint:    "add Delegate=cpuset cpu io memory pids to /etc/systemd/system/user@.service.d/delegate.conf",
+		}
+	}
+	return Result{Name: "cgroup delegation", Status: Pass, Message: "required controllers are delegated"}
+}
+
+// checkSELinux warns when SELinux is enforcing: the MicroShift CRI-O can not
+// relabel the volumes of its pods inside the privileged container then
+func checkSELinux() Result {
+	data, err := os.ReadFile("/sys/fs/selinux/enforce")
+	if os.IsNotExist(err) {
+		return Result{Name: "selinux", Status: Pass, Message: "SELinux is disabled"}
+	}
+	if err != nil {
+		return Result{Name: "selinux", Status: Warn, Message: fmt.Sprintf("unable to read the SELinux mode: %v", err)}
+	}
+	if strings.TrimSpace(string(data)) == "1" {
+		return Result{
+			Name:    "selinux",
+			Status:  Warn,
+			Message: "SELinux is enforcing, pods with volumes may fail to start in the container",
+			Hint:    "run 'sudo setenforce 0' and set SELINUX=permissive in /etc/selinux/config to keep it",
+		}
+	}
+	return Result{Name: "selinux", Status: Pass, Message: "SELinux is permissive"}
+}
+
+func checkModules() []Result {
+	var results []Result
+	for _, m := range requiredModules {
+		// built-in and loaded modules both show up in /sys/module
+		if _, err := os.Stat(filepath.Join("/sys/module", m)); err != nil {
+			results = append(results, Result{
+				Name:    "kernel module " + m,
+				Status:  Fail,
+				Message: fmt.Sprintf("%s is not loaded", m),
+				Hint:    fmt.Sprintf("run 'sudo modprobe %s' and add it to /etc/modules-load.d/minc-rootless.conf", m),
+			})
+			continue
+		}
+		results = append(results, Result{Name: "kernel module " + m, Status: Pass, Message: fmt.Sprintf("%s is loaded", m)})
+	}
+	return results
+}
+
+// freeDisk returns the free bytes of the filesystem holding path, walking up
+// to the closest existing parent.
+func freeDisk(path string) (uint64, error) {
+	if path == "" {
+		path = "/"
+	}
+	var st syscall.Statfs_t
+	for {
+		err := syscall.Statfs(path, &st)
+		if err == nil {
+			return st.Bavail * uint64(st.Bsize), nil
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return 0, err
+		}
+		path = parent
+	}
+}
+
+// hostRoutes reads the IPv4 and IPv6 routes of the host
+func hostRoutes() ([]Route, error) {
+	data, err := os.ReadFile("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	var routes []Route
+	// Iface Destination Gateway Flags RefCnt Use Metric Mask ..., addresses in little endian hex
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		f := strings.Fields(line)
+		if len(f) < 8 {
+			continue
+		}
+		dst, err1 := strconv.ParseUint(f[1], 16, 32)
+		mask, err2 := strconv.ParseUint(f[7], 16, 32)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		addr := netip.AddrFrom4([4]byte{byte(dst), byte(dst >> 8), byte(dst >> 16), byte(dst >> 24)})
+		routes = append(routes, Route{Prefix: netip.PrefixFrom(addr, bits.OnesCount32(uint32(mask))), Iface: f[0]})
+	}
+	// Destination PrefixLen Source SourcePrefixLen NextHop Metric RefCnt Use Flags Iface
+	data, err = os.ReadFile("/proc/net/ipv6_route")
+	if err != nil {
+		// IPv6 may be disabled
+		return routes, nil
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 10 {
+			continue
+		}
+		raw, err1 := hex.DecodeString(f[0])
+		prefixLen, err2 := strconv.ParseUint(f[1], 16, 8)
+		if err1 != nil || err2 != nil || len(raw) != 16 || f[9] == "lo" {
+			continue
+		}
+		addr := netip.AddrFrom16([16]byte(raw))
+		// skip link-local and multicast routes every interface has
+		if addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+			continue
+		}
+		routes = append(routes, Route{Prefix: netip.PrefixFrom(addr, int(prefixLen)), Iface: f[9]})
+	}
+	return routes, nil
+}
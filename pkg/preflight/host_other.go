@@ -0,0 +1,17 @@
+//go:build !linux
+
+package preflight
+
+// checkHost has nothing to check outside Linux, the engine runs in a VM and
+// reports its own cgroup and memory setup.
+func checkHost(rootless bool) []Result {
+	return nil
+}
+
+func freeDisk(path string) (uint64, error) {
+	return 0, errVMStorage
+}
+
+func hostRoutes() ([]Route, error) {
+	return nil, errNoHostRoutes
+}
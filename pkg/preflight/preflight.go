@@ -0,0 +1,117 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/minc-org/minc/pkg/providers"
+)
+
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Result is the outcome of a single preflight check. Hint tells the user how
+// to fix a warning or failure.
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+type Options struct {
+	Provider providers.Provider
+	// ProviderErr is set when the provider could not be initialised, the
+	// provider based checks then report it instead of running
+	ProviderErr error
+	Image       string
+	HTTPPort    int
+	HTTPSPort   int
+	// ClusterCIDR and ServiceCIDR are MicroShift's pod and service networks,
+	// checked against each other, the host routes and EngineSubnets, the
+	// subnets of the engine networks the container joins
+	ClusterCIDR   string
+	ServiceCIDR   string
+	EngineSubnets []string
+	// SkipPorts disables the free port checks, used when the cluster
+	// container already exists and holds the ports itself
+	SkipPorts bool
+}
+
+// Run executes all checks that apply to this host and provider.
+func Run(ctx context.Context, opts *Options) []Result {
+	var results []Result
+	rootless := false
+	if opts.ProviderErr != nil {
+		results = append(results, Result{
+			Name:    "provider",
+			Status:  Fail,
+			Message: opts.ProviderErr.Error(),
+			Hint:    "make sure the container engine is installed and running",
+		})
+	} else {
+		info, err := opts.Provider.Info(ctx)
+		if err != nil {
+			results = append(results, Result{
+				Name:    "provider",
+				Status:  Fail,
+				Message: fmt.Sprintf("%s info: %v", opts.Provider.Name(), err),
+				Hint:    "make sure the container engine is running",
+			})
+		} else {
+			rootless = info.Rootless
+			results = append(results, checkCGroupV2(info), checkRootMode(opts, info))
+			results = append(results, checkSudo(ctx, opts, info)...)
+			results = append(results, checkMemory(info), checkDisk(info))
+			results = append(results, checkImage(ctx, opts))
+		}
+	}
+	if !opts.SkipPorts {
+		results = append(results, checkPorts(opts)...)
+	}
+	results = append(results, checkCIDRs(opts)...)
+	results = append(results, checkHost(rootless)...)
+	return results
+}
+
+// Failed returns an error naming the failed checks, or nil if none failed.
+func Failed(results []Result) error {
+	var failed []string
+	for _, r := range results {
+		if r.Status == Fail {
+			failed = append(failed, fmt.Sprintf("%s (%s)", r.Name, r.Message))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("preflight checks failed: %s, run 'minc doctor' for remediation hints", strings.Join(failed, "; "))
+}
+
+// Print writes results as one line per check with hints for non passing checks.
+func Print(w io.Writer, results []Result) {
+	for _, r := range results {
+		fmt.Fprintf(w, "[%s] %s: %s\n", strings.ToUpper(string(r.Status)), r.Name, r.Message)
+		if r.Status != Pass && r.Hint != "" {
+			fmt.Fprintf(w, "       hint: %s\n", r.Hint)
+		}
+	}
+}
+
+// PrintJSON writes results as an indented JSON array.
+func PrintJSON(w io.Writer, results []Result) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,126 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventSchema versions the JSON events. Fields may be added within a version,
+// renaming or removing one, or changing its meaning, bumps it.
+const EventSchema = "minc.event/v1"
+
+// Event types
+const (
+	EventPhaseStarted  = "phase-started"
+	EventPhaseProgress = "phase-progress"
+	EventPhaseFinished = "phase-finished"
+	EventWarning       = "warning"
+	EventResult        = "result"
+)
+
+// Phase outcomes in phase-finished events
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// Event is one line of the --output json stream
+type Event struct {
+	Schema  string    `json:"schema"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Type    string    `json:"type"`
+	Phase   string    `json:"phase,omitempty"`
+	Title   string    `json:"title,omitempty"`
+	Message string    `json:"message,omitempty"`
+	// Status and ElapsedSeconds are set on phase-finished events
+	Status         string   `json:"status,omitempty"`
+	ElapsedSeconds *float64 `json:"elapsed_seconds,omitempty"`
+	Error          string   `json:"error,omitempty"`
+	Result         *Result  `json:"result,omitempty"`
+}
+
+// Result is the outcome of the command, the last event of the stream
+type Result struct {
+	Success  bool   `json:"success"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+	// KubeConfig is the kubeconfig file holding the cluster, Context its context
+	KubeConfig string     `json:"kubeconfig,omitempty"`
+	Context    string     `json:"context,omitempty"`
+	Endpoints  *Endpoints `json:"endpoints,omitempty"`
+}
+
+// Endpoints are the host side URLs of the cluster
+type Endpoints struct {
+	API   string `json:"api,omitempty"`
+	HTTP  string `json:"http,omitempty"`
+	HTTPS string `json:"https,omitempty"`
+}
+
+// JSON writes newline delimited Events to w
+type JSON struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	command string
+	phase   string
+	started time.Time
+}
+
+// NewJSON returns a reporter writing the events of command to w
+func NewJSON(w io.Writer, command string) *JSON {
+	return &JSON{enc: json.NewEncoder(w), command: command}
+}
+
+func (j *JSON) Start(phase, title string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.phase, j.started = phase, time.Now()
+	j.emit(&Event{Type: EventPhaseStarted, Phase: phase, Title: title})
+}
+
+func (j *JSON) Update(msg string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.emit(&Event{Type: EventPhaseProgress, Phase: j.phase, Message: msg})
+}
+
+func (j *JSON) Warn(msg string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.emit(&Event{Type: EventWarning, Phase: j.phase, Message: msg})
+}
+
+func (j *JSON) Done(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	elapsed := time.Since(j.started).Round(time.Millisecond).Seconds()
+	e := &Event{
+		Type:           EventPhaseFinished,
+		Phase:          j.phase,
+		Status:         StatusOK,
+		ElapsedSeconds: &elapsed,
+	}
+	if err != nil {
+		e.Status, e.Error = StatusFailed, err.Error()
+	}
+	j.emit(e)
+	j.phase = ""
+}
+
+// Result writes the final result event
+func (j *JSON) Result(res *Result) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.emit(&Event{Type: EventResult, Result: res})
+}
+
+// emit writes e, callers hold j.mu
+func (j *JSON) emit(e *Event) {
+	e.Schema, e.Time, e.Command = EventSchema, time.Now().UTC(), j.command
+	// a consumer that went away must not fail the command
+	_ = j.enc.Encode(e)
+}
@@ -0,0 +1,55 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// plain writes one line per event, for CI logs and other non terminals
+type plain struct {
+	mu      sync.Mutex
+	w       io.Writer
+	phase   string
+	started time.Time
+	last    string
+}
+
+func newPlain(w io.Writer) *plain {
+	return &plain{w: w}
+}
+
+func (p *plain) Start(phase, title string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.phase, p.started, p.last = phase, time.Now(), ""
+	fmt.Fprintf(p.w, "[%s] %s ...\n", phase, title)
+}
+
+func (p *plain) Update(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// pull output repeats the same status for every layer poll
+	if msg == p.last {
+		return
+	}
+	p.last = msg
+	fmt.Fprintf(p.w, "[%s] %s\n", p.phase, msg)
+}
+
+func (p *plain) Warn(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "[%s] warning: %s\n", p.phase, msg)
+}
+
+func (p *plain) Done(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(p.w, "[%s] failed after %s\n", p.phase, elapsed(p.started))
+		return
+	}
+	fmt.Fprintf(p.w, "[%s] done in %s\n", p.phase, elapsed(p.started))
+}
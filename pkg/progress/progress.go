@@ -0,0 +1,145 @@
+// Package progress reports the phases of long running minc commands, such as
+// pulling the image and waiting for the MicroShift service during create.
+package progress
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/minc-org/minc/pkg/log"
+	"golang.org/x/term"
+)
+
+// Phases reported by create
+const (
+	PhasePreflight   = "preflight"
+	PhasePull        = "pull"
+	PhaseVerify      = "verify-signature"
+	PhaseCreate      = "create"
+	PhaseStart       = "start"
+	PhaseWaitService = "wait-service"
+	PhaseKubeConfig  = "kubeconfig"
+	PhaseWaitPods    = "wait-pods"
+)
+
+// Phases reported by snapshot save, and by create from a snapshot
+const (
+	PhaseStop          = "stop"
+	PhaseCommit        = "commit"
+	PhaseExportVolumes = "export-volumes"
+	PhaseImportVolumes = "import-volumes"
+)
+
+// Phases reported by upgrade, besides the create ones
+const (
+	PhaseBackupData = "backup-data"
+)
+
+// Phases reported by backup and restore, restore then reports the create
+// phases waiting for the cluster
+const (
+	PhaseStopService  = "stop-service"
+	PhaseBackup       = "backup"
+	PhaseCopy         = "copy"
+	PhaseRestore      = "restore"
+	PhaseStartService = "start-service"
+)
+
+// Phases reported by certs, which then reports the create phases waiting for
+// the cluster
+const (
+	PhaseTrustStore     = "trust-store"
+	PhaseRouterCert     = "router-certificate"
+	PhaseRestartService = "restart-service"
+	PhaseRotateCerts    = "rotate-certificates"
+)
+
+// Phases reported by delete
+const (
+	PhaseDelete           = "delete"
+	PhaseDeleteVolumes    = "delete-volumes"
+	PhaseRemoveKubeConfig = "remove-kubeconfig"
+)
+
+// Reporter reports named phases, one at a time.
+type Reporter interface {
+	// Start begins phase, title is the human readable description
+	Start(phase, title string)
+	// Update reports progress of the running phase, e.g. a pull status line
+	Update(msg string)
+	// Warn reports a warning which does not fail the running phase
+	Warn(msg string)
+	// Done ends the running phase, as failed if err is not nil
+	Done(err error)
+}
+
+// New returns the reporter for f: a live status line when f is a terminal,
+// plain lines otherwise, and only warnings when quiet is set.
+func New(f *os.File, quiet bool) Reporter {
+	if quiet {
+		return Quiet
+	}
+	if term.IsTerminal(int(f.Fd())) {
+		return newTTY(f, int(f.Fd()))
+	}
+	return newPlain(f)
+}
+
+// Quiet drops everything but warnings, which go to the log
+var Quiet Reporter = quiet{}
+
+type quiet struct{}
+
+func (quiet) Start(phase, title string) {}
+func (quiet) Update(msg string)         {}
+func (quiet) Warn(msg string)           { log.Warn(msg) }
+func (quiet) Done(err error)            {}
+
+// Run runs fn as phase, making sure the phase is ended whatever fn returns.
+func Run(r Reporter, phase, title string, fn func() error) error {
+	r.Start(phase, title)
+	err := fn()
+	r.Done(err)
+	return err
+}
+
+// Writer returns a writer reporting every line written to it as an update of
+// the running phase, lines may end with \n or \r as in engine pull output.
+func Writer(r Reporter) io.Writer {
+	return &lineWriter{r: r}
+}
+
+type lineWriter struct {
+	mu  sync.Mutex
+	r   Reporter
+	buf []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(w.buf[:i]); len(line) > 0 {
+			w.r.Update(string(line))
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// elapsed formats the time spent in a phase
+func elapsed(since time.Time) string {
+	d := time.Since(since)
+	if d < time.Minute {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
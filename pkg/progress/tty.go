@@ -0,0 +1,116 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	tickInterval = 100 * time.Millisecond
+	// clearLine returns to the start of the line and erases it
+	clearLine = "\r\033[K"
+)
+
+var frames = []rune(`|/-\`)
+
+// tty redraws a single status line with a spinner, the running phase, its
+// elapsed time and the latest update, and leaves one line per finished phase.
+type tty struct {
+	mu      sync.Mutex
+	w       io.Writer
+	fd      int
+	title   string
+	msg     string
+	started time.Time
+	frame   int
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func newTTY(w io.Writer, fd int) *tty {
+	return &tty{w: w, fd: fd}
+}
+
+func (t *tty) Start(phase, title string) {
+	// a phase still running when the next starts has finished fine
+	t.Done(nil)
+	t.mu.Lock()
+	t.title, t.msg, t.started, t.frame = title, "", time.Now(), 0
+	t.stop, t.stopped = make(chan struct{}), make(chan struct{})
+	t.render()
+	t.mu.Unlock()
+	go t.loop(t.stop, t.stopped)
+}
+
+func (t *tty) loop(stop, stopped chan struct{}) {
+	defer close(stopped)
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			t.mu.Lock()
+			t.frame++
+			t.render()
+			t.mu.Unlock()
+		}
+	}
+}
+
+func (t *tty) Update(msg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.msg = msg
+	t.render()
+}
+
+func (t *tty) Warn(msg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "%s! %s\n", clearLine, msg)
+	if t.stop != nil {
+		t.render()
+	}
+}
+
+func (t *tty) Done(err error) {
+	t.mu.Lock()
+	if t.stop == nil {
+		t.mu.Unlock()
+		return
+	}
+	close(t.stop)
+	stopped := t.stopped
+	t.stop = nil
+	t.mu.Unlock()
+	<-stopped
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	mark := "✓"
+	if err != nil {
+		mark = "✗"
+	}
+	fmt.Fprintf(t.w, "%s%s %s (%s)\n", clearLine, mark, t.title, elapsed(t.started))
+}
+
+// render draws the status line, cut to the terminal width so it never wraps.
+// Callers hold t.mu.
+func (t *tty) render() {
+	line := fmt.Sprintf("%c %s (%s)", frames[t.frame%len(frames)], t.title, elapsed(t.started))
+	if t.msg != "" {
+		line += " " + t.msg
+	}
+	if width, _, err := term.GetSize(t.fd); err == nil && width > 1 {
+		if r := []rune(line); len(r) >= width {
+			line = string(r[:width-1])
+		}
+	}
+	fmt.Fprint(t.w, clearLine+line)
+}
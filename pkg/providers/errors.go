@@ -0,0 +1,85 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/minc-org/minc/pkg/exec"
+)
+
+// Sentinel errors for well known engine failures, check them with errors.Is.
+var (
+	ErrPortAllocated       = errors.New("port is already allocated")
+	ErrNameInUse           = errors.New("container name is already in use")
+	ErrImageNotFound       = errors.New("image not found")
+	ErrPermissionDenied    = errors.New("permission denied")
+	ErrNoSuchContainer     = errors.New("no such container")
+	ErrContainerNotRunning = errors.New("container is not running")
+)
+
+// engineErrors maps fragments of podman and docker error output to sentinels.
+// The fragments are matched against the lower cased output.
+var engineErrors = []struct {
+	sentinel  error
+	fragments []string
+}{
+	{ErrPortAllocated, []string{
+		"port is already allocated",
+		"address already in use",
+		"ports are not available",
+	}},
+	{ErrNameInUse, []string{
+		"is already in use",
+		"conflict. the container name",
+	}},
+	{ErrImageNotFound, []string{
+		"manifest unknown",
+		"name unknown",
+		"repository does not exist",
+		"pull access denied",
+		"no such image",
+		"image not known",
+	}},
+	{ErrNoSuchContainer, []string{
+		"no such container",
+	}},
+	{ErrContainerNotRunning, []string{
+		"container state improper",
+		"is not running",
+	}},
+	{ErrPermissionDenied, []string{
+		"permission denied",
+		"operation not permitted",
+		"a password is required",
+		"a terminal is required",
+	}},
+}
+
+// ClassifyError wraps an engine failure in the matching sentinel error, other
+// errors are returned unchanged.
+func ClassifyError(err error) error {
+	var runErr *exec.RunError
+	if !errors.As(err, &runErr) {
+		return err
+	}
+	out := runErr.Stderr
+	if len(out) == 0 {
+		out = runErr.Output
+	}
+	lower := strings.ToLower(string(out))
+	for _, e := range engineErrors {
+		for _, f := range e.fragments {
+			if strings.Contains(lower, f) {
+				return fmt.Errorf("%w: %w", e.sentinel, err)
+			}
+		}
+	}
+	return err
+}
+
+// Output is like exec.Output, but classifies engine failures, see ClassifyError.
+func Output(cmd exec.Cmd) ([]byte, error) {
+	out, err := exec.Output(cmd)
+	return out, ClassifyError(err)
+}
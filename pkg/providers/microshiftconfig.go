@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minc-org/minc/pkg/minc/types"
+)
+
+// MincConfigDropIn is where the MicroShift config generated by minc is
+// mounted, after the custom config (00-custom-config.yaml) so the minc flags
+// win
+const MincConfigDropIn = "/etc/microshift/config.d/10-minc.yaml"
+
+// IngressConfigDropIn is written into the running container by 'minc certs
+// trust'. Containers recreated from the recorded cluster settings, e.g. by
+// upgrade or a snapshot, get the setting from WriteMincConfig instead.
+const IngressConfigDropIn = "/etc/microshift/config.d/20-minc-ingress.yaml"
+
+// IngressConfig returns the drop-in making the kubernetes.io/tls secret of
+// the openshift-ingress namespace the router's default certificate
+func IngressConfig(secret string) []byte {
+	return []byte(fmt.Sprintf("# generated by 'minc certs trust'\ningress:\n  certificateSecret: %s\n", secret))
+}
+
+// WriteMincConfig renders MicroShift's pod and service networks, the API
+// server's extra subject alternative names and the router certificate secret
+// of cType into a config.d drop-in in the user's minc config directory and
+// returns its path, or an empty path when all are the MicroShift defaults.
+// A drop-in written before is rewritten as an empty one then, containers may
+// still mount it.
+func WriteMincConfig(cType *types.CreateType) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(configDir, "minc")
+	path := filepath.Join(dir, "microshift-minc.yaml")
+	var b strings.Builder
+	b.WriteString("# generated by minc from --cluster-cidr, --service-cidr, --api-san and 'minc certs trust'\n")
+	if cType.ClusterCIDR != "" || cType.ServiceCIDR != "" {
+		b.WriteString("network:\n")
+	}
+	if cType.ClusterCIDR != "" {
+		fmt.Fprintf(&b, "  clusterNetwork:\n    - %s\n", cType.ClusterCIDR)
+	}
+	if cType.ServiceCIDR != "" {
+		fmt.Fprintf(&b, "  serviceNetwork:\n    - %s\n", cType.ServiceCIDR)
+	}
+	if len(cType.APISANs) > 0 {
+		b.WriteString("apiServer:\n  subjectAltNames:\n")
+		for _, san := range cType.APISANs {
+			fmt.Fprintf(&b, "    - %q\n", san)
+		}
+	}
+	if cType.RouterCertSecret != "" {
+		fmt.Fprintf(&b, "ingress:\n  certificateSecret: %s\n", cType.RouterCertSecret)
+	}
+	if cType.ClusterCIDR == "" && cType.ServiceCIDR == "" && len(cType.APISANs) == 0 && cType.RouterCertSecret == "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", nil
+		}
+		// an empty document would replace the whole config when merged
+		b.WriteString("{}\n")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("writing the minc MicroShift config: %w", err)
+	}
+	return path, nil
+}
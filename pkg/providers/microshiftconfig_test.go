@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"os"
+	"testing"
+
+	"github.com/minc-org/minc/pkg/minc/types"
+)
+
+func TestWriteMincConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	steps := []struct {
+		name     string
+		cType    *types.CreateType
+		wantPath bool
+		want     string
+	}{
+		{
+			name:  "defaults",
+			cType: &types.CreateType{},
+		},
+		{
+			name: "all settings",
+			cType: &types.CreateType{
+				ClusterCIDR:      "10.44.0.0/16",
+				ServiceCIDR:      "10.45.0.0/16",
+				APISANs:          []string{"minc.example.com", "192.168.1.10"},
+				RouterCertSecret: "minc-router-certs",
+			},
+			wantPath: true,
+			want: `# generated by minc from --cluster-cidr, --service-cidr, --api-san and 'minc certs trust'
+network:
+  clusterNetwork:
+    - 10.44.0.0/16
+  serviceNetwork:
+    - 10.45.0.0/16
+apiServer:
+  subjectAltNames:
+    - "minc.example.com"
+    - "192.168.1.10"
+ingress:
+  certificateSecret: minc-router-certs
+`,
+		},
+		{
+			name:     "defaults after settings",
+			cType:    &types.CreateType{},
+			wantPath: true,
+			want: `# generated by minc from --cluster-cidr, --service-cidr, --api-san and 'minc certs trust'
+{}
+`,
+		},
+	}
+	// the steps run in order, the last one rewrites the drop-in of the previous
+	for _, step := range steps {
+		path, err := WriteMincConfig(step.cType)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if (path != "") != step.wantPath {
+			t.Fatalf("%s: got path %q, want a path %v", step.name, path, step.wantPath)
+		}
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != step.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", step.name, data, step.want)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package moby
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/minc-org/minc/pkg/minc/types"
@@ -17,80 +19,106 @@ import (
 // Provider implements provider.Provider
 // see NewProvider
 type provider struct {
-	info *providers.ProviderInfo
+	cmder exec.Cmder
+	info  *providers.ProviderInfo
 }
 
-func New() (providers.Provider, error) {
-	pInfo, err := getProviderInfo()
+// New builds a Docker provider running its commands with cmder.
+func New(ctx context.Context, cmder exec.Cmder) (providers.Provider, error) {
+	p := &provider{cmder: cmder}
+	pInfo, err := p.getProviderInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &provider{pInfo}, nil
+	p.info = pInfo
+	return p, nil
 }
 
 func (p *provider) Name() string {
 	return "docker"
 }
 
-func (p *provider) Info() (*providers.ProviderInfo, error) {
-	return getProviderInfo()
+func (p *provider) Info(ctx context.Context) (*providers.ProviderInfo, error) {
+	return p.getProviderInfo(ctx)
 }
 
-func (p *provider) ImageExists(image string) bool {
-	cmd := exec.Command("docker",
+func (p *provider) RawInfo(ctx context.Context) ([]byte, error) {
+	cmd := p.dockerCmd(ctx, "info", "--format", "json")
+	return providers.Output(cmd)
+}
+
+func (p *provider) ImageExists(ctx context.Context, image string) bool {
+	cmd := p.dockerCmd(ctx,
 		providers.ImageExistOptions(image)...,
 	)
-	_, err := exec.Output(cmd)
+	_, err := providers.Output(cmd)
 	if err != nil {
 		return false
 	}
 	return true
 }
 
-func (p *provider) PullImage(image string) error {
+func (p *provider) PullImage(ctx context.Context, image string, w io.Writer) error {
 	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
 		return err
 	}
-	if p.ImageExists(image) {
+	if p.ImageExists(ctx, image) {
 		return nil
 	}
-	cmd := exec.Command("docker",
+	cmd := p.dockerCmd(ctx,
 		providers.PullOptions(image)...,
 	)
-	out, err := exec.Output(cmd)
-	if err != nil {
-		return err
-	}
-	log.Debug(string(out))
-	return nil
+	cmd.SetStdout(w)
+	cmd.SetStderr(w)
+	return providers.ClassifyError(cmd.Run())
+}
+
+func (p *provider) PullImageWithPolicy(ctx context.Context, image, policy string, w io.Writer) error {
+	return fmt.Errorf("signature policies are only supported by podman, verify the image with a signature key instead")
 }
 
-func (p *provider) Create(cType *types.CreateType) error {
+func (p *provider) Create(ctx context.Context, cType *types.CreateType) error {
 	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
 		return err
 	}
-	if out, _ := p.List(); len(out) == 0 {
+	if out, _ := p.List(ctx); len(out) == 0 {
+		mincConfig, err := providers.WriteMincConfig(cType)
+		if err != nil {
+			return err
+		}
 		cOptions := &providers.COptions{
 			ContainerName:       constants.ContainerName,
-			ImageName:           constants.GetUShiftImage(cType.UShiftImage, cType.UShiftVersion),
+			ImageName:           cType.ImageRef(),
 			UShiftConfig:        cType.UShiftConfig,
 			HttpPort:            cType.HTTPPort,
 			HttpsPort:           cType.HTTPSPort,
 			DisableOverlayCache: cType.DisableOverlayCache,
+			PersistentData:      cType.PersistentData,
+			Network:             cType.Network,
+			IP:                  cType.IP,
+			MincConfig:          mincConfig,
+			ExposeAPI:           len(cType.APISANs) > 0,
 		}
-		cmd := exec.Command("docker",
+		cmd := p.dockerCmd(ctx,
 			providers.CreateOptions(cOptions)...,
 		)
-		out, err := exec.Output(cmd)
+		out, err := providers.Output(cmd)
 		if err != nil {
 			return err
 		}
 		log.Debug(string(out))
 	}
-	cmd := exec.Command("docker",
+	return nil
+}
+
+func (p *provider) Start(ctx context.Context) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx,
 		providers.StartOptions(constants.ContainerName)...,
 	)
-	out, err := exec.Output(cmd)
+	out, err := providers.Output(cmd)
 	if err != nil {
 		return err
 	}
@@ -98,43 +126,58 @@ func (p *provider) Create(cType *types.CreateType) error {
 	return nil
 }
 
-func (p *provider) WaitForMicroShiftService() error {
+func (p *provider) WaitForMicroShiftService(ctx context.Context, backoff retry.Backoff) error {
 	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
 		return err
 	}
-	cmdFunc := func() error {
-		cmd := exec.Command("docker",
+	cmdFunc := func(ctx context.Context) error {
+		cmd := p.dockerCmd(ctx,
 			providers.ServiceWaitOption("microshift", constants.ContainerName)...,
 		)
-		out, err := exec.Output(cmd)
+		out, err := providers.Output(cmd)
 		if err != nil {
-			return err
+			return providers.ServiceWaitError(err)
 		}
 
 		log.Debug(string(out))
 		return nil
 	}
-	return retry.Retry(cmdFunc, providers.MicroShiftServiceMaxRetries, providers.MicroShiftServiceInitialRetryDelay)
+	return retry.Do(ctx, backoff, cmdFunc)
 }
 
-func (p *provider) GetKubeConfig() ([]byte, error) {
+func (p *provider) GetKubeConfig(ctx context.Context) ([]byte, error) {
 	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
 		return nil, err
 	}
-	cmd := exec.Command("docker",
+	cmd := p.dockerCmd(ctx,
 		providers.KubeConfigOption(constants.ContainerName, constants.HostName)...,
 	)
-	return exec.Output(cmd)
+	return providers.Output(cmd)
+}
+
+func (p *provider) Stop(ctx context.Context) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx,
+		providers.StopOptions(constants.ContainerName)...,
+	)
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
 }
 
-func (p *provider) Delete() error {
+func (p *provider) Delete(ctx context.Context) error {
 	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
 		return err
 	}
-	cmd := exec.Command("docker",
+	cmd := p.dockerCmd(ctx,
 		providers.DeleteOptions(constants.ContainerName)...,
 	)
-	out, err := exec.Output(cmd)
+	out, err := providers.Output(cmd)
 	if err != nil {
 		return err
 	}
@@ -142,15 +185,15 @@ func (p *provider) Delete() error {
 	return nil
 }
 
-func (p *provider) List() ([]byte, error) {
+func (p *provider) List(ctx context.Context) ([]byte, error) {
 	var out []byte
 	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
 		return out, err
 	}
-	cmd := exec.Command("docker",
+	cmd := p.dockerCmd(ctx,
 		providers.ListOptions(constants.ContainerName)...,
 	)
-	out, err := exec.Output(cmd)
+	out, err := providers.Output(cmd)
 	if err != nil {
 		return out, err
 	}
@@ -163,9 +206,233 @@ func (p *provider) List() ([]byte, error) {
 	return out, nil
 }
 
-func getProviderInfo() (*providers.ProviderInfo, error) {
-	cmd := exec.Command("docker", "info", "--format", "json")
-	out, err := exec.Output(cmd)
+func (p *provider) VolumeExists(ctx context.Context, name string) bool {
+	cmd := p.dockerCmd(ctx,
+		providers.VolumeInspectOptions(name)...,
+	)
+	_, err := providers.Output(cmd)
+	return err == nil
+}
+
+func (p *provider) DeleteVolume(ctx context.Context, name string) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx,
+		providers.VolumeRemoveOptions(name)...,
+	)
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) InspectNetwork(ctx context.Context, name string) ([]byte, error) {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return nil, err
+	}
+	cmd := p.dockerCmd(ctx,
+		providers.NetworkInspectOptions(name)...,
+	)
+	return providers.Output(cmd)
+}
+
+func (p *provider) CreateNetwork(ctx context.Context, name, subnet string) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx,
+		providers.NetworkCreateOptions(name, subnet)...,
+	)
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) DeleteNetwork(ctx context.Context, name string) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx,
+		providers.NetworkRemoveOptions(name)...,
+	)
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) ConnectNetwork(ctx context.Context, name, container string) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx,
+		providers.NetworkConnectOptions(name, container)...,
+	)
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) DisconnectNetwork(ctx context.Context, name, container string) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx,
+		providers.NetworkDisconnectOptions(name, container)...,
+	)
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) Logs(ctx context.Context, w io.Writer, lType *types.LogsType) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	args, err := providers.LogsOptions(constants.ContainerName, lType)
+	if err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx, args...)
+	cmd.SetStdout(w)
+	cmd.SetStderr(w)
+	return providers.ClassifyError(cmd.Run())
+}
+
+func (p *provider) Inspect(ctx context.Context) ([]byte, error) {
+	cmd := p.dockerCmd(ctx,
+		providers.InspectOptions(constants.ContainerName)...,
+	)
+	return providers.Output(cmd)
+}
+
+func (p *provider) Exec(ctx context.Context, command ...string) ([]byte, error) {
+	cmd := p.dockerCmd(ctx,
+		providers.ExecOptions(constants.ContainerName, command...)...,
+	)
+	return providers.Output(cmd)
+}
+
+func (p *provider) ExecStream(ctx context.Context, in io.Reader, out io.Writer, command ...string) error {
+	args := providers.ExecOptions(constants.ContainerName, command...)
+	if in != nil {
+		args = providers.ExecStreamOptions(constants.ContainerName, command...)
+	}
+	cmd := p.dockerCmd(ctx, args...)
+	if in != nil {
+		cmd.SetStdin(in)
+	}
+	cmd.SetStdout(out)
+	return providers.ClassifyError(cmd.Run())
+}
+
+func (p *provider) Commit(ctx context.Context, image string) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx,
+		providers.CommitOptions(constants.ContainerName, image)...,
+	)
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) ImageDigests(ctx context.Context, image string) ([]string, error) {
+	cmd := p.dockerCmd(ctx,
+		providers.ImageDigestsOptions(image)...,
+	)
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return nil, err
+	}
+	var digests []string
+	if err := json.Unmarshal(out, &digests); err != nil {
+		return nil, fmt.Errorf("parsing the digests of %s: %w", image, err)
+	}
+	return digests, nil
+}
+
+func (p *provider) RemoveImage(ctx context.Context, image string) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx,
+		providers.ImageRemoveOptions(image)...,
+	)
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+// ExportVolume archives the volume with tar from the MicroShift image since
+// docker has no volume export
+func (p *provider) ExportVolume(ctx context.Context, name string, w io.Writer) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	image, err := p.containerImage(ctx)
+	if err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx,
+		providers.VolumeArchiveOptions(name, image)...,
+	)
+	cmd.SetStdout(w)
+	return providers.ClassifyError(cmd.Run())
+}
+
+func (p *provider) ImportVolume(ctx context.Context, name string, r io.Reader) error {
+	if err := checkCGroupsAndRootFulMode(p.info); err != nil {
+		return err
+	}
+	image, err := p.containerImage(ctx)
+	if err != nil {
+		return err
+	}
+	cmd := p.dockerCmd(ctx,
+		providers.VolumeExtractOptions(name, image)...,
+	)
+	cmd.SetStdin(r)
+	_, err = providers.Output(cmd)
+	return err
+}
+
+// containerImage returns the image of the MicroShift container, it has the
+// tar used to archive volumes
+func (p *provider) containerImage(ctx context.Context) (string, error) {
+	cmd := p.dockerCmd(ctx,
+		providers.ContainerImageOptions(constants.ContainerName)...,
+	)
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (p *provider) getProviderInfo(ctx context.Context) (*providers.ProviderInfo, error) {
+	out, err := p.RawInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -173,6 +440,8 @@ func getProviderInfo() (*providers.ProviderInfo, error) {
 	type Response struct {
 		CgroupsVersion  string   `json:"CgroupVersion"`
 		SecurityOptions []string `json:"SecurityOptions"`
+		MemTotal        int64    `json:"MemTotal"`
+		DockerRootDir   string   `json:"DockerRootDir"`
 	}
 
 	var res Response
@@ -194,8 +463,10 @@ func getProviderInfo() (*providers.ProviderInfo, error) {
 	}
 
 	return &providers.ProviderInfo{
-		Rootless: rootless,
-		CGroupV2: cGroupV2,
+		Rootless:    rootless,
+		CGroupV2:    cGroupV2,
+		MemTotal:    res.MemTotal,
+		StorageRoot: res.DockerRootDir,
 	}, nil
 
 }
@@ -216,3 +487,7 @@ func checkCGroupsAndRootFulMode(pInfo *providers.ProviderInfo) error {
 func (p *provider) String() string {
 	return "docker"
 }
+
+func (p *provider) dockerCmd(ctx context.Context, args ...string) exec.Cmd {
+	return p.cmder.CommandContext(ctx, "docker", args...)
+}
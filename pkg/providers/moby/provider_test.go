@@ -0,0 +1,311 @@
+package moby
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/minc-org/minc/pkg/exec"
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/providers"
+)
+
+const (
+	rootfulInfo  = `{"CgroupVersion":"2","SecurityOptions":["name=seccomp,profile=builtin","name=cgroupns"],"MemTotal":8589934592,"DockerRootDir":"/var/lib/docker"}`
+	rootlessInfo = `{"CgroupVersion":"2","SecurityOptions":["name=seccomp,profile=builtin","name=rootless","name=cgroupns"]}`
+	cgroupV1Info = `{"CgroupVersion":"1","SecurityOptions":["name=seccomp,profile=builtin"]}`
+
+	listOutput = "microshift 127.0.0.1:9080->80/tcp running\n"
+	image      = "quay.io/minc-org/minc:test"
+	kubeConfig = "/var/lib/microshift/resources/kubeadmin/127.0.0.1.nip.io/kubeconfig"
+)
+
+var (
+	listArgv   = []string{"docker", "ps", "-a", "-f", "label=io.x-openshift.microshift.cluster=microshift", "--format", "{{.Names}} {{.Ports}} {{.State}}"}
+	createArgv = []string{"docker", "create", "--hostname", "127.0.0.1.nip.io",
+		"--label", "io.x-openshift.microshift.cluster=microshift",
+		"-it", "--privileged",
+		"-p", "127.0.0.1:9080:80",
+		"-p", "127.0.0.1:9443:443",
+		"-p", "6443:6443",
+		"-v", "/var/lib/containers/storage:/host-container:ro,rshared",
+		"-v", exec.Wildcard,
+		"--name", "microshift", image}
+)
+
+func TestMain(m *testing.M) {
+	if err := log.SetLogger(&log.Options{Level: "error"}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newProvider(t *testing.T, info string) (providers.Provider, *exec.FakeCmder) {
+	t.Helper()
+	f := exec.NewFakeCmder()
+	f.Expect("docker", "info", "--format", "json").Return(info)
+	p, err := New(context.Background(), f)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return p, f
+}
+
+func create(ctx context.Context, p providers.Provider) error {
+	return p.Create(ctx, &types.CreateType{
+		Image:     image,
+		HTTPPort:  9080,
+		HTTPSPort: 9443,
+		APISANs:   []string{"minc.example.com"},
+	})
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		script func(f *exec.FakeCmder)
+		run    func(ctx context.Context, p providers.Provider) error
+		want   [][]string
+	}{
+		{
+			name: "create",
+			script: func(f *exec.FakeCmder) {
+				f.Expect(listArgv...)
+				f.Expect(createArgv...).Return("0123456789ab\n")
+			},
+			run:  create,
+			want: [][]string{listArgv, createArgv},
+		},
+		{
+			name: "create skips a running cluster",
+			script: func(f *exec.FakeCmder) {
+				f.Expect(listArgv...).Return(listOutput)
+			},
+			run:  create,
+			want: [][]string{listArgv},
+		},
+		{
+			name: "delete",
+			script: func(f *exec.FakeCmder) {
+				f.Expect("docker", "rm", "-f", "microshift")
+			},
+			run: func(ctx context.Context, p providers.Provider) error {
+				return p.Delete(ctx)
+			},
+			want: [][]string{{"docker", "rm", "-f", "microshift"}},
+		},
+		{
+			name: "stop",
+			script: func(f *exec.FakeCmder) {
+				f.Expect("docker", "stop", "microshift")
+			},
+			run: func(ctx context.Context, p providers.Provider) error {
+				return p.Stop(ctx)
+			},
+			want: [][]string{{"docker", "stop", "microshift"}},
+		},
+		{
+			name: "list",
+			script: func(f *exec.FakeCmder) {
+				f.Expect(listArgv...).Return(listOutput)
+			},
+			run: func(ctx context.Context, p providers.Provider) error {
+				out, err := p.List(ctx)
+				if string(out) != listOutput {
+					return errors.New("unexpected list output: " + string(out))
+				}
+				return err
+			},
+			want: [][]string{listArgv},
+		},
+		{
+			name: "list of a stopped cluster",
+			script: func(f *exec.FakeCmder) {
+				f.Expect(listArgv...).Return("microshift  exited\n")
+			},
+			run: func(ctx context.Context, p providers.Provider) error {
+				if _, err := p.List(ctx); err == nil {
+					return errors.New("List of a stopped cluster succeeded")
+				}
+				return nil
+			},
+			want: [][]string{listArgv},
+		},
+		{
+			name: "pull",
+			script: func(f *exec.FakeCmder) {
+				f.Expect("docker", "image", "inspect", image).Fail(1, "Error response from daemon: No such image: "+image)
+				f.Expect("docker", "pull", image).Return("Status: Downloaded newer image for " + image + "\n")
+			},
+			run: func(ctx context.Context, p providers.Provider) error {
+				var progress bytes.Buffer
+				if err := p.PullImage(ctx, image, &progress); err != nil {
+					return err
+				}
+				if !bytes.Contains(progress.Bytes(), []byte("Downloaded newer image")) {
+					return errors.New("pull progress not written: " + progress.String())
+				}
+				return nil
+			},
+			want: [][]string{{"docker", "image", "inspect", image}, {"docker", "pull", image}},
+		},
+		{
+			name: "kubeconfig",
+			script: func(f *exec.FakeCmder) {
+				f.Expect("docker", "exec", "microshift", "cat", kubeConfig).Return("apiVersion: v1\nkind: Config\n")
+			},
+			run: func(ctx context.Context, p providers.Provider) error {
+				out, err := p.GetKubeConfig(ctx)
+				if string(out) != "apiVersion: v1\nkind: Config\n" {
+					return errors.New("unexpected kubeconfig: " + string(out))
+				}
+				return err
+			},
+			want: [][]string{{"docker", "exec", "microshift", "cat", kubeConfig}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_CONFIG_HOME", "")
+			p, f := newProvider(t, rootfulInfo)
+			tt.script(f)
+			if err := tt.run(context.Background(), p); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// the first call is the info one of New
+			calls := f.Calls()[1:]
+			if !callsMatch(tt.want, calls) {
+				t.Errorf("commands:\n got %q\nwant %q", calls, tt.want)
+			}
+		})
+	}
+}
+
+// callsMatch compares the argv of commands, exec.Wildcard matches any
+// argument, e.g. the host path of the generated config drop-in
+func callsMatch(want, calls [][]string) bool {
+	if len(want) != len(calls) {
+		return false
+	}
+	for i := range want {
+		if len(want[i]) != len(calls[i]) {
+			return false
+		}
+		for j := range want[i] {
+			if want[i][j] != exec.Wildcard && want[i][j] != calls[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name   string
+		argv   []string
+		stderr string
+		run    func(ctx context.Context, p providers.Provider) error
+		want   error
+	}{
+		{
+			name:   "name in use",
+			argv:   createArgv,
+			stderr: `Error response from daemon: Conflict. The container name "/microshift" is already in use by container "4f2a". You have to remove (or rename) that container to be able to reuse that name.`,
+			run:    create,
+			want:   providers.ErrNameInUse,
+		},
+		{
+			name:   "port allocated",
+			argv:   []string{"docker", "start", "microshift"},
+			stderr: "Error response from daemon: driver failed programming external connectivity on endpoint microshift: Bind for 127.0.0.1:9443 failed: port is already allocated",
+			run: func(ctx context.Context, p providers.Provider) error {
+				return p.Start(ctx)
+			},
+			want: providers.ErrPortAllocated,
+		},
+		{
+			name:   "image not found",
+			argv:   []string{"docker", "pull", image},
+			stderr: "Error response from daemon: manifest for " + image + " not found: manifest unknown: manifest unknown",
+			run: func(ctx context.Context, p providers.Provider) error {
+				return p.PullImage(ctx, image, &bytes.Buffer{})
+			},
+			want: providers.ErrImageNotFound,
+		},
+		{
+			name:   "no such container",
+			argv:   []string{"docker", "stop", "microshift"},
+			stderr: "Error response from daemon: No such container: microshift",
+			run: func(ctx context.Context, p providers.Provider) error {
+				return p.Stop(ctx)
+			},
+			want: providers.ErrNoSuchContainer,
+		},
+		{
+			name:   "not running",
+			argv:   []string{"docker", "exec", "microshift", "cat", kubeConfig},
+			stderr: "Error response from daemon: container 4f2a is not running",
+			run: func(ctx context.Context, p providers.Provider) error {
+				_, err := p.GetKubeConfig(ctx)
+				return err
+			},
+			want: providers.ErrContainerNotRunning,
+		},
+		{
+			name:   "permission denied",
+			argv:   []string{"docker", "rm", "-f", "microshift"},
+			stderr: "permission denied while trying to connect to the Docker daemon socket at unix:///var/run/docker.sock",
+			run: func(ctx context.Context, p providers.Provider) error {
+				return p.Delete(ctx)
+			},
+			want: providers.ErrPermissionDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_CONFIG_HOME", "")
+			p, f := newProvider(t, rootfulInfo)
+			f.Expect("docker", "image", "inspect", image).Fail(1, "Error response from daemon: No such image: "+image)
+			f.Expect(listArgv...)
+			f.Expect(tt.argv...).Fail(1, tt.stderr)
+			err := tt.run(context.Background(), p)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			var runErr *exec.RunError
+			if !errors.As(err, &runErr) {
+				t.Errorf("%v does not wrap the *exec.RunError", err)
+			}
+		})
+	}
+}
+
+func TestRequirements(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    string
+		wantErr bool
+	}{
+		{name: "rootful", info: rootfulInfo},
+		{name: "rootless", info: rootlessInfo, wantErr: true},
+		{name: "cgroup v1", info: cgroupV1Info, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, f := newProvider(t, tt.info)
+			f.Expect("docker", "rm", "-f", "microshift")
+			if err := p.Delete(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("Delete error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && len(f.Calls()) != 1 {
+				t.Errorf("ran %q despite the failed requirements", f.Calls()[1:])
+			}
+		})
+	}
+}
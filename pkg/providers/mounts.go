@@ -0,0 +1,35 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Mount is a mount of a container as reported by podman and docker inspect
+type Mount struct {
+	Type        string `json:"Type"`
+	Name        string `json:"Name"`
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+}
+
+// VolumeMounts returns the named and anonymous volumes mounted in the
+// container described by the inspect output of either engine
+func VolumeMounts(inspect []byte) ([]Mount, error) {
+	var containers []struct {
+		Mounts []Mount `json:"Mounts"`
+	}
+	if err := json.Unmarshal(inspect, &containers); err != nil {
+		return nil, fmt.Errorf("parsing container inspect output: %w", err)
+	}
+	if len(containers) == 0 {
+		return nil, ErrNoSuchContainer
+	}
+	var volumes []Mount
+	for _, m := range containers[0].Mounts {
+		if m.Type == "volume" {
+			volumes = append(volumes, m)
+		}
+	}
+	return volumes, nil
+}
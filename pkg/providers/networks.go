@@ -0,0 +1,36 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// NetworkSubnets returns the subnets of the network described by the network
+// inspect output of either engine: docker reports them in IPAM.Config,
+// podman in subnets
+func NetworkSubnets(inspect []byte) ([]string, error) {
+	var networks []struct {
+		IPAM struct {
+			Config []struct {
+				Subnet string `json:"Subnet"`
+			} `json:"Config"`
+		} `json:"IPAM"`
+		Subnets []struct {
+			Subnet string `json:"subnet"`
+		} `json:"subnets"`
+	}
+	if err := json.Unmarshal(inspect, &networks); err != nil {
+		return nil, fmt.Errorf("parsing network inspect output: %w", err)
+	}
+	if len(networks) == 0 {
+		return nil, fmt.Errorf("network inspect returned no network")
+	}
+	var subnets []string
+	for _, c := range networks[0].IPAM.Config {
+		subnets = append(subnets, c.Subnet)
+	}
+	for _, s := range networks[0].Subnets {
+		subnets = append(subnets, s.Subnet)
+	}
+	return subnets, nil
+}
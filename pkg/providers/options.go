@@ -2,7 +2,10 @@ package providers
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/minc-org/minc/pkg/constants"
+	"github.com/minc-org/minc/pkg/minc/types"
 )
 
 type COptions struct {
@@ -12,6 +15,18 @@ type COptions struct {
 	HttpPort            int
 	HttpsPort           int
 	DisableOverlayCache bool
+	// PersistentData mounts constants.DataVolume as MicroShift's data directory
+	PersistentData bool
+	// Network is the network the container joins instead of the default
+	// bridge, with the static address IP if set
+	Network string
+	IP      string
+	// MincConfig is the host path of the drop-in holding the MicroShift
+	// settings of the minc flags, see WriteMincConfig
+	MincConfig string
+	// ExposeAPI publishes the API server on all host interfaces instead of
+	// 127.0.0.1, for clients reaching it through an extra SAN
+	ExposeAPI bool
 	// HostContainerStorage is the host path to the container engine's graph root (e.g. Podman Store.GraphRoot).
 	// When empty, the default rootful path /var/lib/containers/storage is used.
 	HostContainerStorage string
@@ -38,12 +53,16 @@ func CreateOptions(r *COptions) []string {
 	// need to bind with all the interfaces
 	httpPortOption := "127.0.0.1:%d:80"
 	httpsPortOption := "127.0.0.1:%d:443"
+	apiPortOption := "127.0.0.1:6443:6443"
 	if r.HttpPort < 1024 {
 		httpPortOption = "%d:80"
 	}
 	if r.HttpsPort < 1024 {
 		httpsPortOption = "%d:443"
 	}
+	if r.ExposeAPI {
+		apiPortOption = "6443:6443"
+	}
 	createOptions := []string{
 		"create",
 		"--hostname", constants.HostName,
@@ -51,7 +70,7 @@ func CreateOptions(r *COptions) []string {
 		"-it", "--privileged",
 		"-p", fmt.Sprintf(httpPortOption, r.HttpPort),
 		"-p", fmt.Sprintf(httpsPortOption, r.HttpsPort),
-		"-p", "127.0.0.1:6443:6443",
+		"-p", apiPortOption,
 	}
 
 	if r.AllowRootless {
@@ -84,7 +103,18 @@ func CreateOptions(r *COptions) []string {
 		// Use named volume for better macOS/Docker compatibility
 		// This allows CRI-O to function without accessing host storage
 		// Note: Named volumes don't support bind options like 'rshared'
-		createOptions = append(createOptions, "-v", "minc-container-storage:/host-container")
+		createOptions = append(createOptions, "-v", fmt.Sprintf("%s:/host-container", constants.StorageVolume))
+	}
+
+	if r.Network != "" {
+		createOptions = append(createOptions, "--network", r.Network)
+		if r.IP != "" {
+			createOptions = append(createOptions, "--ip", r.IP)
+		}
+	}
+
+	if r.PersistentData {
+		createOptions = append(createOptions, "-v", fmt.Sprintf("%s:%s", constants.DataVolume, constants.UShiftDataDir))
 	}
 
 	// Mount custom MicroShift config if provided
@@ -92,6 +122,9 @@ func CreateOptions(r *COptions) []string {
 		createOptions = append(createOptions, "-v",
 			fmt.Sprintf("%s:/etc/microshift/config.d/00-custom-config.yaml:ro,rshared", r.UShiftConfig))
 	}
+	if r.MincConfig != "" {
+		createOptions = append(createOptions, "-v", fmt.Sprintf("%s:%s:ro", r.MincConfig, MincConfigDropIn))
+	}
 
 	return append(createOptions,
 		"--name", r.ContainerName, r.ImageName)
@@ -104,6 +137,13 @@ func StartOptions(containerName string) []string {
 	}
 }
 
+func StopOptions(containerName string) []string {
+	return []string{
+		"stop",
+		containerName,
+	}
+}
+
 func PullOptions(imageName string) []string {
 	return []string{
 		"pull",
@@ -111,6 +151,16 @@ func PullOptions(imageName string) []string {
 	}
 }
 
+// PullPolicyOptions pulls the image verifying its signatures with a
+// containers-policy.json(5) file, podman only
+func PullPolicyOptions(imageName, policy string) []string {
+	return []string{
+		"pull",
+		"--signature-policy", policy,
+		imageName,
+	}
+}
+
 func ImageExistOptions(imageName string) []string {
 	return []string{
 		"image",
@@ -134,7 +184,37 @@ func KubeConfigOption(containerName, hostname string) []string {
 		"exec",
 		containerName,
 		"cat",
-		fmt.Sprintf("/var/lib/microshift/resources/kubeadmin/%s/kubeconfig", hostname),
+		KubeConfigPath(hostname),
+	}
+}
+
+// KubeConfigPath is the kubeadmin kubeconfig MicroShift generates in the
+// container for the API server name hostname, the hostname and every SAN
+func KubeConfigPath(hostname string) string {
+	return fmt.Sprintf("/var/lib/microshift/resources/kubeadmin/%s/kubeconfig", hostname)
+}
+
+func ExecOptions(containerName string, command ...string) []string {
+	return append([]string{
+		"exec",
+		containerName,
+	}, command...)
+}
+
+// ExecStreamOptions is ExecOptions keeping stdin open, to stream data into the command
+func ExecStreamOptions(containerName string, command ...string) []string {
+	return append([]string{
+		"exec",
+		"-i",
+		containerName,
+	}, command...)
+}
+
+func InspectOptions(containerName string) []string {
+	return []string{
+		"container",
+		"inspect",
+		containerName,
 	}
 }
 
@@ -146,6 +226,152 @@ func DeleteOptions(containerName string) []string {
 	}
 }
 
+func VolumeInspectOptions(volumeName string) []string {
+	return []string{
+		"volume",
+		"inspect",
+		volumeName,
+	}
+}
+
+// NetworkCreateOptions creates the cluster's network, labelled like the
+// container, with the subnet if set
+func NetworkCreateOptions(networkName, subnet string) []string {
+	options := []string{
+		"network",
+		"create",
+		"--label", fmt.Sprintf("%s=%s", constants.LabelKey, constants.ContainerName),
+	}
+	if subnet != "" {
+		options = append(options, "--subnet", subnet)
+	}
+	return append(options, networkName)
+}
+
+func NetworkInspectOptions(networkName string) []string {
+	return []string{
+		"network",
+		"inspect",
+		networkName,
+	}
+}
+
+func NetworkRemoveOptions(networkName string) []string {
+	return []string{
+		"network",
+		"rm",
+		networkName,
+	}
+}
+
+func NetworkConnectOptions(networkName, containerName string) []string {
+	return []string{
+		"network",
+		"connect",
+		networkName,
+		containerName,
+	}
+}
+
+func NetworkDisconnectOptions(networkName, containerName string) []string {
+	return []string{
+		"network",
+		"disconnect",
+		networkName,
+		containerName,
+	}
+}
+
+func VolumeRemoveOptions(volumeName string) []string {
+	return []string{
+		"volume",
+		"rm",
+		volumeName,
+	}
+}
+
+func CommitOptions(containerName, imageName string) []string {
+	return []string{
+		"commit",
+		containerName,
+		imageName,
+	}
+}
+
+// ImageDigestsOptions prints the repository digests of the image as a JSON array
+func ImageDigestsOptions(imageName string) []string {
+	return []string{
+		"image",
+		"inspect",
+		"--format", "{{json .RepoDigests}}",
+		imageName,
+	}
+}
+
+func ImageRemoveOptions(imageName string) []string {
+	return []string{
+		"rmi",
+		imageName,
+	}
+}
+
+// ContainerImageOptions prints the image the container was created from
+func ContainerImageOptions(containerName string) []string {
+	return []string{
+		"container",
+		"inspect",
+		"--format", "{{.Config.Image}}",
+		containerName,
+	}
+}
+
+// volumeMountPoint is where VolumeArchiveOptions and VolumeExtractOptions
+// mount the volume in their helper container
+const volumeMountPoint = "/volume"
+
+// VolumeArchiveOptions runs a throwaway container from imageName writing a
+// tar archive of the volume to stdout, for engines without volume export
+func VolumeArchiveOptions(volumeName, imageName string) []string {
+	return []string{
+		"run", "--rm",
+		"-v", fmt.Sprintf("%s:%s", volumeName, volumeMountPoint),
+		"--entrypoint", "tar",
+		imageName,
+		"-C", volumeMountPoint, "-cf", "-", ".",
+	}
+}
+
+// VolumeExtractOptions is the counterpart of VolumeArchiveOptions, it
+// extracts a tar archive read from stdin into the volume
+func VolumeExtractOptions(volumeName, imageName string) []string {
+	return []string{
+		"run", "--rm", "-i",
+		"-v", fmt.Sprintf("%s:%s", volumeName, volumeMountPoint),
+		"--entrypoint", "tar",
+		imageName,
+		"-C", volumeMountPoint, "-xf", "-",
+	}
+}
+
+// VolumeExportOptions writes a tar archive of the volume to stdout (podman)
+func VolumeExportOptions(volumeName string) []string {
+	return []string{
+		"volume",
+		"export",
+		volumeName,
+	}
+}
+
+// VolumeImportOptions extracts a tar archive read from stdin into the volume (podman)
+func VolumeImportOptions(volumeName string) []string {
+	return []string{
+		"volume",
+		"import",
+		volumeName,
+		"-",
+	}
+}
+
 func ListOptions(containerName string) []string {
 	return []string{
 		"ps",
@@ -154,3 +380,55 @@ func ListOptions(containerName string) []string {
 		"--format", "{{.Names}} {{.Ports}} {{.State}}",
 	}
 }
+
+func JournalOptions(containerName, unit string, follow bool, since string, tail int) []string {
+	options := []string{
+		"exec",
+		containerName,
+		"journalctl",
+		"-u", unit,
+		"--no-pager",
+	}
+	if follow {
+		options = append(options, "-f")
+	}
+	if since != "" {
+		// journalctl wants relative times in the past prefixed with '-'
+		if _, err := time.ParseDuration(since); err == nil {
+			since = "-" + since
+		}
+		options = append(options, "--since", since)
+	}
+	if tail > 0 {
+		options = append(options, "-n", fmt.Sprintf("%d", tail))
+	}
+	return options
+}
+
+func ContainerLogsOptions(containerName string, follow bool, since string, tail int) []string {
+	options := []string{"logs"}
+	if follow {
+		options = append(options, "-f")
+	}
+	if since != "" {
+		options = append(options, "--since", since)
+	}
+	if tail > 0 {
+		options = append(options, "--tail", fmt.Sprintf("%d", tail))
+	}
+	return append(options, containerName)
+}
+
+// LogsOptions maps a logs component to the engine arguments reading it: the
+// microshift and crio units are read with journalctl inside the container,
+// container reads the engine's own container logs.
+func LogsOptions(containerName string, lType *types.LogsType) ([]string, error) {
+	switch lType.Component {
+	case "microshift", "crio":
+		return JournalOptions(containerName, lType.Component, lType.Follow, lType.Since, lType.Tail), nil
+	case "container":
+		return ContainerLogsOptions(containerName, lType.Follow, lType.Since, lType.Tail), nil
+	default:
+		return nil, fmt.Errorf("unknown logs component %q, use one of microshift, crio or container", lType.Component)
+	}
+}
@@ -1,8 +1,10 @@
 package podman
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -20,25 +22,28 @@ import (
 
 // Provider implements provider.Provider
 type provider struct {
+	cmder         exec.Cmder
 	info          *providers.ProviderInfo
 	useSudo       bool
 	allowRootless bool
 }
 
-// New builds a Podman provider. When allowRootless is true, minc stays on the user's rootless
-// Podman (no sudo) and skips the rootful-only guard; MicroShift may still fail at runtime.
-func New(allowRootless bool) (providers.Provider, error) {
-	rootlessRuntime, err := rootlessFromUserPodman()
+// New builds a Podman provider running its commands with cmder. When allowRootless is true, minc
+// stays on the user's rootless Podman (no sudo) and skips the rootful-only guard; MicroShift may
+// still fail at runtime.
+func New(ctx context.Context, cmder exec.Cmder, allowRootless bool) (providers.Provider, error) {
+	rootlessRuntime, err := rootlessFromUserPodman(ctx, cmder)
 	if err != nil {
 		return nil, err
 	}
 	useSudo := rootlessRuntime && !allowRootless
 
 	p := &provider{
+		cmder:         cmder,
 		allowRootless: allowRootless,
 		useSudo:       useSudo,
 	}
-	info, err := p.fetchProviderInfo()
+	info, err := p.fetchProviderInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -46,9 +51,9 @@ func New(allowRootless bool) (providers.Provider, error) {
 	return p, nil
 }
 
-func rootlessFromUserPodman() (bool, error) {
-	cmd := exec.Command("podman", "info", "--format", "{{.Host.Security.Rootless}}")
-	out, err := exec.Output(cmd)
+func rootlessFromUserPodman(ctx context.Context, cmder exec.Cmder) (bool, error) {
+	cmd := cmder.CommandContext(ctx, "podman", "info", "--format", "{{.Host.Security.Rootless}}")
+	out, err := providers.Output(cmd)
 	if err != nil {
 		return false, err
 	}
@@ -59,60 +64,82 @@ func (p *provider) Name() string {
 	return "podman"
 }
 
-func (p *provider) Info() (*providers.ProviderInfo, error) {
-	return p.fetchProviderInfo()
+func (p *provider) Info(ctx context.Context) (*providers.ProviderInfo, error) {
+	return p.fetchProviderInfo(ctx)
 }
 
-func (p *provider) ImageExists(image string) bool {
-	cmd := p.podmanCmd(providers.ImageExistOptions(image))
-	_, err := exec.Output(cmd)
+func (p *provider) RawInfo(ctx context.Context) ([]byte, error) {
+	cmd := p.podmanCmd(ctx, []string{"info", "--format", "json"})
+	return providers.Output(cmd)
+}
+
+func (p *provider) ImageExists(ctx context.Context, image string) bool {
+	cmd := p.podmanCmd(ctx, providers.ImageExistOptions(image))
+	_, err := providers.Output(cmd)
 	if err != nil {
 		return false
 	}
 	return true
 }
 
-func (p *provider) PullImage(image string) error {
-	if err := p.checkCGroupsAndRootFulMode(); err != nil {
+func (p *provider) PullImage(ctx context.Context, image string, w io.Writer) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
 		return err
 	}
-	if p.ImageExists(image) {
+	if p.ImageExists(ctx, image) {
 		return nil
 	}
-	cmd := p.podmanCmd(providers.PullOptions(image))
-	out, err := exec.Output(cmd)
-	if err != nil {
+	// podman reports pull progress on stderr
+	cmd := p.podmanCmd(ctx, providers.PullOptions(image))
+	cmd.SetStdout(w)
+	cmd.SetStderr(w)
+	return providers.ClassifyError(cmd.Run())
+}
+
+func (p *provider) PullImageWithPolicy(ctx context.Context, image, policy string, w io.Writer) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
 		return err
 	}
-	log.Debug(string(out))
-	return nil
+	cmd := p.podmanCmd(ctx, providers.PullPolicyOptions(image, policy))
+	cmd.SetStdout(w)
+	cmd.SetStderr(w)
+	return providers.ClassifyError(cmd.Run())
 }
 
-func (p *provider) storeGraphRoot() (string, error) {
-	cmd := p.podmanCmd([]string{"info", "--format", "{{.Store.GraphRoot}}"})
-	out, err := exec.Output(cmd)
+func (p *provider) storeGraphRoot(ctx context.Context) (string, error) {
+	cmd := p.podmanCmd(ctx, []string{"info", "--format", "{{.Store.GraphRoot}}"})
+	out, err := providers.Output(cmd)
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(string(out)), nil
 }
 
-func (p *provider) Create(cType *types.CreateType) error {
-	if err := p.checkCGroupsAndRootFulMode(); err != nil {
+func (p *provider) Create(ctx context.Context, cType *types.CreateType) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
 		return err
 	}
-	if out, _ := p.List(); len(out) == 0 {
-		graphRoot, err := p.storeGraphRoot()
+	if out, _ := p.List(ctx); len(out) == 0 {
+		graphRoot, err := p.storeGraphRoot(ctx)
 		if err != nil {
 			return fmt.Errorf("podman store graph root: %w", err)
 		}
+		mincConfig, err := providers.WriteMincConfig(cType)
+		if err != nil {
+			return err
+		}
 		cOptions := &providers.COptions{
 			ContainerName:        constants.ContainerName,
-			ImageName:            constants.GetUShiftImage(cType.UShiftImage, cType.UShiftVersion),
+			ImageName:            cType.ImageRef(),
 			UShiftConfig:         cType.UShiftConfig,
 			HttpPort:             cType.HTTPPort,
 			HttpsPort:            cType.HTTPSPort,
 			DisableOverlayCache:  cType.DisableOverlayCache,
+			PersistentData:       cType.PersistentData,
+			Network:              cType.Network,
+			IP:                   cType.IP,
+			MincConfig:           mincConfig,
+			ExposeAPI:            len(cType.APISANs) > 0,
 			HostContainerStorage: graphRoot,
 			AllowRootless:        p.allowRootless,
 		}
@@ -125,15 +152,22 @@ func (p *provider) Create(cType *types.CreateType) error {
 			cOptions.RootlessCRIOConfig = rlConf.crioConf
 			cOptions.RootlessCrunWrapper = rlConf.crunWrapper
 		}
-		cmd := p.podmanCmd(providers.CreateOptions(cOptions))
-		out, err := exec.Output(cmd)
+		cmd := p.podmanCmd(ctx, providers.CreateOptions(cOptions))
+		out, err := providers.Output(cmd)
 		if err != nil {
 			return err
 		}
 		log.Debug(string(out))
 	}
-	cmd := p.podmanCmd(providers.StartOptions(constants.ContainerName))
-	out, err := exec.Output(cmd)
+	return nil
+}
+
+func (p *provider) Start(ctx context.Context) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, providers.StartOptions(constants.ContainerName))
+	out, err := providers.Output(cmd)
 	if err != nil {
 		return err
 	}
@@ -141,37 +175,37 @@ func (p *provider) Create(cType *types.CreateType) error {
 	return nil
 }
 
-func (p *provider) WaitForMicroShiftService() error {
-	if err := p.checkCGroupsAndRootFulMode(); err != nil {
+func (p *provider) WaitForMicroShiftService(ctx context.Context, backoff retry.Backoff) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
 		return err
 	}
-	cmdFunc := func() error {
-		cmd := p.podmanCmd(providers.ServiceWaitOption("microshift", constants.ContainerName))
-		out, err := exec.Output(cmd)
+	cmdFunc := func(ctx context.Context) error {
+		cmd := p.podmanCmd(ctx, providers.ServiceWaitOption("microshift", constants.ContainerName))
+		out, err := providers.Output(cmd)
 		if err != nil {
-			return err
+			return providers.ServiceWaitError(err)
 		}
 
 		log.Debug(string(out))
 		return nil
 	}
-	return retry.Retry(cmdFunc, providers.MicroShiftServiceMaxRetries, providers.MicroShiftServiceInitialRetryDelay)
+	return retry.Do(ctx, backoff, cmdFunc)
 }
 
-func (p *provider) GetKubeConfig() ([]byte, error) {
-	if err := p.checkCGroupsAndRootFulMode(); err != nil {
+func (p *provider) GetKubeConfig(ctx context.Context) ([]byte, error) {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
 		return nil, err
 	}
-	cmd := p.podmanCmd(providers.KubeConfigOption(constants.ContainerName, constants.HostName))
-	return exec.Output(cmd)
+	cmd := p.podmanCmd(ctx, providers.KubeConfigOption(constants.ContainerName, constants.HostName))
+	return providers.Output(cmd)
 }
 
-func (p *provider) Delete() error {
-	if err := p.checkCGroupsAndRootFulMode(); err != nil {
+func (p *provider) Stop(ctx context.Context) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
 		return err
 	}
-	cmd := p.podmanCmd(providers.DeleteOptions(constants.ContainerName))
-	out, err := exec.Output(cmd)
+	cmd := p.podmanCmd(ctx, providers.StopOptions(constants.ContainerName))
+	out, err := providers.Output(cmd)
 	if err != nil {
 		return err
 	}
@@ -179,13 +213,26 @@ func (p *provider) Delete() error {
 	return nil
 }
 
-func (p *provider) List() ([]byte, error) {
+func (p *provider) Delete(ctx context.Context) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, providers.DeleteOptions(constants.ContainerName))
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) List(ctx context.Context) ([]byte, error) {
 	var out []byte
-	if err := p.checkCGroupsAndRootFulMode(); err != nil {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
 		return out, err
 	}
-	cmd := p.podmanCmd(providers.ListOptions(constants.ContainerName))
-	out, err := exec.Output(cmd)
+	cmd := p.podmanCmd(ctx, providers.ListOptions(constants.ContainerName))
+	out, err := providers.Output(cmd)
 	if err != nil {
 		return out, err
 	}
@@ -199,9 +246,185 @@ func (p *provider) List() ([]byte, error) {
 	return out, nil
 }
 
-func (p *provider) fetchProviderInfo() (*providers.ProviderInfo, error) {
-	cmd := p.podmanCmd([]string{"info", "--format", "json"})
-	out, err := exec.Output(cmd)
+func (p *provider) VolumeExists(ctx context.Context, name string) bool {
+	cmd := p.podmanCmd(ctx, providers.VolumeInspectOptions(name))
+	_, err := providers.Output(cmd)
+	return err == nil
+}
+
+func (p *provider) DeleteVolume(ctx context.Context, name string) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, providers.VolumeRemoveOptions(name))
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) InspectNetwork(ctx context.Context, name string) ([]byte, error) {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return nil, err
+	}
+	cmd := p.podmanCmd(ctx, providers.NetworkInspectOptions(name))
+	return providers.Output(cmd)
+}
+
+func (p *provider) CreateNetwork(ctx context.Context, name, subnet string) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, providers.NetworkCreateOptions(name, subnet))
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) DeleteNetwork(ctx context.Context, name string) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, providers.NetworkRemoveOptions(name))
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) ConnectNetwork(ctx context.Context, name, container string) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, providers.NetworkConnectOptions(name, container))
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) DisconnectNetwork(ctx context.Context, name, container string) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, providers.NetworkDisconnectOptions(name, container))
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) Logs(ctx context.Context, w io.Writer, lType *types.LogsType) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	args, err := providers.LogsOptions(constants.ContainerName, lType)
+	if err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, args)
+	cmd.SetStdout(w)
+	cmd.SetStderr(w)
+	return providers.ClassifyError(cmd.Run())
+}
+
+func (p *provider) Inspect(ctx context.Context) ([]byte, error) {
+	cmd := p.podmanCmd(ctx, providers.InspectOptions(constants.ContainerName))
+	return providers.Output(cmd)
+}
+
+func (p *provider) Exec(ctx context.Context, command ...string) ([]byte, error) {
+	cmd := p.podmanCmd(ctx, providers.ExecOptions(constants.ContainerName, command...))
+	return providers.Output(cmd)
+}
+
+func (p *provider) ExecStream(ctx context.Context, in io.Reader, out io.Writer, command ...string) error {
+	args := providers.ExecOptions(constants.ContainerName, command...)
+	if in != nil {
+		args = providers.ExecStreamOptions(constants.ContainerName, command...)
+	}
+	cmd := p.podmanCmd(ctx, args)
+	if in != nil {
+		cmd.SetStdin(in)
+	}
+	cmd.SetStdout(out)
+	return providers.ClassifyError(cmd.Run())
+}
+
+func (p *provider) Commit(ctx context.Context, image string) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, providers.CommitOptions(constants.ContainerName, image))
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) ImageDigests(ctx context.Context, image string) ([]string, error) {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return nil, err
+	}
+	cmd := p.podmanCmd(ctx, providers.ImageDigestsOptions(image))
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return nil, err
+	}
+	var digests []string
+	if err := json.Unmarshal(out, &digests); err != nil {
+		return nil, fmt.Errorf("parsing the digests of %s: %w", image, err)
+	}
+	return digests, nil
+}
+
+func (p *provider) RemoveImage(ctx context.Context, image string) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, providers.ImageRemoveOptions(image))
+	out, err := providers.Output(cmd)
+	if err != nil {
+		return err
+	}
+	log.Debug(string(out))
+	return nil
+}
+
+func (p *provider) ExportVolume(ctx context.Context, name string, w io.Writer) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, providers.VolumeExportOptions(name))
+	cmd.SetStdout(w)
+	return providers.ClassifyError(cmd.Run())
+}
+
+func (p *provider) ImportVolume(ctx context.Context, name string, r io.Reader) error {
+	if err := p.checkCGroupsAndRootFulMode(ctx); err != nil {
+		return err
+	}
+	cmd := p.podmanCmd(ctx, providers.VolumeImportOptions(name))
+	cmd.SetStdin(r)
+	_, err := providers.Output(cmd)
+	return err
+}
+
+func (p *provider) fetchProviderInfo(ctx context.Context) (*providers.ProviderInfo, error) {
+	out, err := p.RawInfo(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -209,10 +432,14 @@ func (p *provider) fetchProviderInfo() (*providers.ProviderInfo, error) {
 	type Response struct {
 		Host struct {
 			CgroupsVersion string `json:"cgroupVersion"`
+			MemTotal       int64  `json:"memTotal"`
 			Security       struct {
 				Rootless bool `json:"rootless"`
 			} `json:"security"`
 		} `json:"host"`
+		Store struct {
+			GraphRoot string `json:"graphRoot"`
+		} `json:"store"`
 	}
 
 	var res Response
@@ -226,14 +453,16 @@ func (p *provider) fetchProviderInfo() (*providers.ProviderInfo, error) {
 	}
 
 	return &providers.ProviderInfo{
-		Rootless: res.Host.Security.Rootless,
-		CGroupV2: cGroupV2,
+		Rootless:    res.Host.Security.Rootless,
+		CGroupV2:    cGroupV2,
+		MemTotal:    res.Host.MemTotal,
+		StorageRoot: res.Store.GraphRoot,
 	}, nil
 }
 
-func (p *provider) checkCGroupsAndRootFulMode() error {
+func (p *provider) checkCGroupsAndRootFulMode(ctx context.Context) error {
 	if p.info == nil {
-		info, err := p.fetchProviderInfo()
+		info, err := p.fetchProviderInfo(ctx)
 		if err != nil {
 			return err
 		}
@@ -255,12 +484,12 @@ func (p *provider) String() string {
 	return "podman"
 }
 
-func (p *provider) podmanCmd(args []string) exec.Cmd {
+func (p *provider) podmanCmd(ctx context.Context, args []string) exec.Cmd {
 	if p.useSudo && runtime.GOOS == "linux" {
 		log.Debug("Running with sudo:", "podman", strings.Join(args, " "))
-		return exec.Command("sudo", append([]string{"podman"}, args...)...)
+		return p.cmder.CommandContext(ctx, "sudo", append([]string{"podman"}, args...)...)
 	}
-	return exec.Command("podman", args...)
+	return p.cmder.CommandContext(ctx, "podman", args...)
 }
 
 // rootlessConfigs holds the host paths for all rootless configuration files.
@@ -0,0 +1,35 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PublishedPort returns the host port the container port (tcp) is published
+// on, from the container inspect output of either engine. It returns 0 when
+// the port is not published.
+func PublishedPort(inspect []byte, containerPort int) (int, error) {
+	var containers []struct {
+		NetworkSettings struct {
+			Ports map[string][]struct {
+				HostIP   string `json:"HostIp"`
+				HostPort string `json:"HostPort"`
+			} `json:"Ports"`
+		} `json:"NetworkSettings"`
+	}
+	if err := json.Unmarshal(inspect, &containers); err != nil {
+		return 0, fmt.Errorf("parsing container inspect output: %w", err)
+	}
+	if len(containers) == 0 {
+		return 0, ErrNoSuchContainer
+	}
+	for _, b := range containers[0].NetworkSettings.Ports[fmt.Sprintf("%d/tcp", containerPort)] {
+		port, err := strconv.Atoi(strings.TrimSpace(b.HostPort))
+		if err == nil && port > 0 {
+			return port, nil
+		}
+	}
+	return 0, nil
+}
@@ -1,22 +1,68 @@
 package providers
 
 import (
+	"context"
+	"io"
+
 	"github.com/minc-org/minc/pkg/minc/types"
+	"github.com/minc-org/minc/pkg/retry"
 )
 
 type Provider interface {
 	Name() string
-	Info() (*ProviderInfo, error)
-	ImageExists(string) bool
-	PullImage(image string) error
-	Create(cType *types.CreateType) error
-	WaitForMicroShiftService() error
-	GetKubeConfig() ([]byte, error)
-	Delete() error
-	List() ([]byte, error)
+	Info(ctx context.Context) (*ProviderInfo, error)
+	// RawInfo returns the engine's own info output in JSON
+	RawInfo(ctx context.Context) ([]byte, error)
+	ImageExists(ctx context.Context, image string) bool
+	// PullImage pulls image unless present, streaming the engine's pull
+	// progress to w
+	PullImage(ctx context.Context, image string, w io.Writer) error
+	// PullImageWithPolicy pulls image, even if present, verifying its
+	// signatures with the containers-policy.json(5) file policy
+	PullImageWithPolicy(ctx context.Context, image, policy string, w io.Writer) error
+	// ImageDigests returns the repository digests of the image, as
+	// <repository>@<digest>
+	ImageDigests(ctx context.Context, image string) ([]string, error)
+	// Create creates the MicroShift container unless it already exists
+	Create(ctx context.Context, cType *types.CreateType) error
+	Start(ctx context.Context) error
+	WaitForMicroShiftService(ctx context.Context, backoff retry.Backoff) error
+	GetKubeConfig(ctx context.Context) ([]byte, error)
+	Stop(ctx context.Context) error
+	Delete(ctx context.Context) error
+	List(ctx context.Context) ([]byte, error)
+	VolumeExists(ctx context.Context, name string) bool
+	DeleteVolume(ctx context.Context, name string) error
+	// InspectNetwork returns the engine's inspect output of the network,
+	// failing if it does not exist
+	InspectNetwork(ctx context.Context, name string) ([]byte, error)
+	CreateNetwork(ctx context.Context, name, subnet string) error
+	DeleteNetwork(ctx context.Context, name string) error
+	// ConnectNetwork attaches another container to the network
+	ConnectNetwork(ctx context.Context, name, container string) error
+	DisconnectNetwork(ctx context.Context, name, container string) error
+	Logs(ctx context.Context, w io.Writer, lType *types.LogsType) error
+	Inspect(ctx context.Context) ([]byte, error)
+	// Exec runs command inside the MicroShift container and returns its stdout
+	Exec(ctx context.Context, command ...string) ([]byte, error)
+	// ExecStream runs command inside the MicroShift container with stdin read
+	// from in, if not nil, and stdout written to out, for data too large to buffer
+	ExecStream(ctx context.Context, in io.Reader, out io.Writer, command ...string) error
+	// Commit saves the MicroShift container's filesystem as image
+	Commit(ctx context.Context, image string) error
+	RemoveImage(ctx context.Context, image string) error
+	// ExportVolume writes a tar archive of the volume's content to w
+	ExportVolume(ctx context.Context, name string, w io.Writer) error
+	// ImportVolume extracts the tar archive read from r into the volume
+	ImportVolume(ctx context.Context, name string, r io.Reader) error
 }
 
 type ProviderInfo struct {
 	Rootless bool
 	CGroupV2 bool
+	// MemTotal is the memory available to the engine in bytes, on macOS and
+	// Windows this is the memory of the engine's VM
+	MemTotal int64
+	// StorageRoot is the engine's image and container storage directory
+	StorageRoot string
 }
@@ -1,6 +1,11 @@
 package register
 
 import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/minc-org/minc/pkg/exec"
 	"github.com/minc-org/minc/pkg/providers"
 	"github.com/minc-org/minc/pkg/providers/moby"
 	"github.com/minc-org/minc/pkg/providers/podman"
@@ -8,14 +13,39 @@ import (
 	"github.com/spf13/viper"
 )
 
-func Register(provider string) (providers.Provider, error) {
+var (
+	cmderOnce sync.Once
+	cmder     exec.Cmder
+	cmderErr  error
+)
+
+func Register(ctx context.Context, provider string) (providers.Provider, error) {
 	allowRootless := viper.GetBool("allow-rootless") || rootlessmarker.Present()
+	// MINC_EXEC_RECORD and MINC_EXEC_REPLAY swap the command runner, see
+	// exec.CmderFromEnv. All providers of the process and exec.Command share it.
+	cmderOnce.Do(func() {
+		cmder, cmderErr = exec.CmderFromEnv()
+		if cmderErr == nil {
+			exec.DefaultCmder = cmder
+		}
+	})
+	if cmderErr != nil {
+		return nil, cmderErr
+	}
 	switch provider {
 	case "podman":
-		return podman.New(allowRootless)
+		return podman.New(ctx, cmder, allowRootless)
 	case "docker":
-		return moby.New()
+		return moby.New(ctx, cmder)
 	default:
-		return podman.New(allowRootless)
+		return podman.New(ctx, cmder, allowRootless)
+	}
+}
+
+// Close releases the command runner, e.g. the transcript of MINC_EXEC_RECORD
+func Close() error {
+	if c, ok := cmder.(io.Closer); ok {
+		return c.Close()
 	}
+	return nil
 }
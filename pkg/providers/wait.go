@@ -1,13 +1,44 @@
 package providers
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
 
-// MicroShiftServiceMaxRetries bounds how long we poll systemctl is-active for the microshift
+	"github.com/minc-org/minc/pkg/log"
+	"github.com/minc-org/minc/pkg/retry"
+)
+
+// DefaultServiceWaitTimeout bounds how long we poll systemctl is-active for the microshift
 // unit inside the container. While the unit is "activating", is-active exits non-zero (often 3),
 // so each poll is a retry. Rootless or slow storage can keep the unit activating for many minutes.
-//
-// pkg/retry uses linear backoff: sleep = InitialDelay * attempt.
-// Total wait = InitialDelay * (1+2+...+(N-1)) = 2s * (17*18/2) = 306s ≈ 5 min.
-const MicroShiftServiceMaxRetries = 18
+const DefaultServiceWaitTimeout = 5 * time.Minute
+
+// DefaultServiceWaitInterval is the delay after the first poll, it doubles up to
+// serviceWaitMaxInterval so a slow start is not polled needlessly often.
+const DefaultServiceWaitInterval = 2 * time.Second
+
+const serviceWaitMaxInterval = 15 * time.Second
+
+// ServiceWaitBackoff returns the backoff used to poll the microshift unit.
+func ServiceWaitBackoff(timeout, interval time.Duration) retry.Backoff {
+	return retry.Backoff{
+		Strategy:     retry.Exponential,
+		InitialDelay: interval,
+		MaxDelay:     serviceWaitMaxInterval,
+		Jitter:       0.1,
+		MaxElapsed:   timeout,
+		OnAttempt: func(a retry.Attempt) {
+			log.Debug(fmt.Sprintf("Attempt: %d", a.Number), "failed:", a.Err, "retrying in", a.NextDelay)
+		},
+	}
+}
 
-const MicroShiftServiceInitialRetryDelay = 2 * time.Second
+// ServiceWaitError marks failures polling can not fix as permanent, e.g. the
+// container is gone or stopped, so the wait does not burn its whole budget.
+func ServiceWaitError(err error) error {
+	if errors.Is(err, ErrNoSuchContainer) || errors.Is(err, ErrContainerNotRunning) {
+		return retry.Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+// Package redact masks secrets in command output, logs and diagnostic files.
+package redact
+
+import (
+	"bytes"
+	"regexp"
+)
+
+const redacted = "<redacted>"
+
+// value matches a value quoted with escaped quotes, e.g. in a JSON log record
+// or an annotation, a quoted value, or an unquoted one. Unquoted values end at
+// a quote or backslash so an embedding string stays closed.
+const value = `(\\".*?\\"|"(?:[^"\\]|\\.)*"|'[^']*'|[^\s,}"'\\]+)`
+
+var (
+	// privateKeyPattern matches whole PEM encoded private keys
+	privateKeyPattern = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+	// bearerPattern matches bearer tokens in headers and command lines
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+	// secretValuePattern matches the value of well known secret keys in
+	// YAML, JSON, INI and env style files, e.g. `token: abc` or `"password":"abc"`
+	secretValuePattern = regexp.MustCompile(
+		`(?i)((?:token|password|passwd|secret|client-key-data|client-certificate-data|certificate-authority-data|pullsecret|auth)(?:\\?["'])?\s*[:=]\s*)` + value)
+	// envVarPattern matches the value of a Kubernetes env var,
+	// {"name":"DB_PASSWORD","value":"abc"}, also in an applied configuration
+	// annotation where the quotes are escaped
+	envVarPattern = regexp.MustCompile(`(\\?"name\\?"\s*:\s*\\?"[^"\\]*\\?"\s*,\s*\\?"value\\?"\s*:\s*)` + value)
+	// envListPattern matches the KEY=value list of a container inspect,
+	// "Env": ["DB_PASSWORD=abc", ...]
+	envListPattern = regexp.MustCompile(`("Env"\s*:\s*\[)([^\]]*)\]`)
+	// envEntryPattern matches an entry of such a list
+	envEntryPattern = regexp.MustCompile(`"([^"=]*)=(?:[^"\\]|\\.)*"`)
+)
+
+// Redact masks private keys, tokens and other secrets in data, and the values
+// of environment variables, which often hold credentials.
+func Redact(data []byte) []byte {
+	data = privateKeyPattern.ReplaceAll(data, []byte(redacted))
+	data = bearerPattern.ReplaceAll(data, []byte("${1}"+redacted))
+	data = replaceValues(secretValuePattern, data)
+	data = replaceValues(envVarPattern, data)
+	return envListPattern.ReplaceAllFunc(data, func(list []byte) []byte {
+		return envEntryPattern.ReplaceAll(list, []byte(`"${1}=`+redacted+`"`))
+	})
+}
+
+// replaceValues masks the value of every match of pattern, its first group
+// is the key kept and its second the value, keeping the value's quotes
+func replaceValues(pattern *regexp.Regexp, data []byte) []byte {
+	return pattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		sub := pattern.FindSubmatch(match)
+		key, val := sub[1], sub[2]
+		out := append([]byte{}, key...)
+		switch {
+		case bytes.HasPrefix(val, []byte(`\"`)):
+			return append(out, `\"`+redacted+`\"`...)
+		case val[0] == '"' || val[0] == '\'':
+			return append(append(append(out, val[0]), redacted...), val[0])
+		}
+		return append(out, redacted...)
+	})
+}
+
+// Strings returns args with each of them redacted
+func Strings(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = string(Redact([]byte(arg)))
+	}
+	return redacted
+}
@@ -0,0 +1,80 @@
+// Package reference parses OCI image references such as
+// quay.io/minc-org/minc:4.19.0-okd-scos.17-amd64 or
+// quay.io/minc-org/minc@sha256:<hex>.
+package reference
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrDigestMismatch is returned when an image does not have the expected digest
+var ErrDigestMismatch = errors.New("image digest mismatch")
+
+var (
+	// repositoryRegexp matches an optional registry host[:port] followed by
+	// lowercase path components
+	repositoryRegexp = regexp.MustCompile(`^(?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagRegexp        = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+	digestRegexp     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$|^sha512:[a-f0-9]{128}$`)
+)
+
+// Reference is a parsed image reference. Tag and Digest are empty when the
+// reference does not have them, a reference can have both.
+type Reference struct {
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// Parse parses an image reference
+func Parse(s string) (*Reference, error) {
+	ref := &Reference{}
+	name, digest, found := strings.Cut(s, "@")
+	if found {
+		if err := ValidateDigest(digest); err != nil {
+			return nil, fmt.Errorf("invalid image reference %q: %w", s, err)
+		}
+		ref.Digest = digest
+	}
+	// a colon after the last slash starts the tag, one before is a registry port
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+		if !tagRegexp.MatchString(ref.Tag) {
+			return nil, fmt.Errorf("invalid image reference %q: invalid tag %q", s, ref.Tag)
+		}
+	}
+	if !repositoryRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid image reference %q: invalid repository %q", s, name)
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+// ValidateDigest returns an error unless digest is a sha256 or sha512 digest
+// such as sha256:<64 hex characters>
+func ValidateDigest(digest string) error {
+	if !digestRegexp.MatchString(digest) {
+		return fmt.Errorf("invalid digest %q, expected sha256:<64 hex characters>", digest)
+	}
+	return nil
+}
+
+// Pinned reports whether the reference has a tag or a digest, and so names
+// one image rather than a repository
+func (r *Reference) Pinned() bool {
+	return r.Tag != "" || r.Digest != ""
+}
+
+func (r *Reference) String() string {
+	s := r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
@@ -0,0 +1,192 @@
+// Package registry lists the tags of an image repository with the OCI
+// distribution API, authenticating with anonymous bearer tokens as quay.io
+// and Docker Hub require.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// pageSize is the number of tags requested per page, registries may return fewer
+const pageSize = 1000
+
+// Client queries OCI registries
+type Client struct {
+	HTTP *http.Client
+	// PlainHTTP talks to the registry over http instead of https, for local registries
+	PlainHTTP bool
+}
+
+// New returns a Client with a request timeout
+func New(plainHTTP bool) *Client {
+	return &Client{HTTP: &http.Client{Timeout: 30 * time.Second}, PlainHTTP: plainHTTP}
+}
+
+// SplitRepository splits an image repository such as quay.io/minc-org/minc
+// into the registry host and the repository name. Repositories without a
+// registry host are on Docker Hub.
+func SplitRepository(repo string) (host, name string) {
+	host, name, found := strings.Cut(repo, "/")
+	if !found || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		host, name = "registry-1.docker.io", repo
+		if !strings.Contains(name, "/") {
+			name = "library/" + name
+		}
+	}
+	return host, name
+}
+
+// Tags returns all the tags of the image repository, following the
+// pagination links of the registry
+func (c *Client) Tags(ctx context.Context, repo string) ([]string, error) {
+	host, name := SplitRepository(repo)
+	scheme := "https"
+	if c.PlainHTTP {
+		scheme = "http"
+	}
+	next := &url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     fmt.Sprintf("/v2/%s/tags/list", name),
+		RawQuery: fmt.Sprintf("n=%d", pageSize),
+	}
+	var tags []string
+	token := ""
+	for next != nil {
+		resp, err := c.get(ctx, next, token)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && token == "" {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if token, err = c.token(ctx, challenge); err != nil {
+				return nil, fmt.Errorf("authenticating to %s: %w", host, err)
+			}
+			continue
+		}
+		page, err := decodeTags(resp)
+		if err != nil {
+			return nil, fmt.Errorf("listing the tags of %s: %w", repo, err)
+		}
+		tags = append(tags, page...)
+		next, err = nextPage(next, resp.Header.Get("Link"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+func (c *Client) get(ctx context.Context, u *url.URL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.HTTP.Do(req)
+}
+
+func decodeTags(resp *http.Response) ([]string, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var list struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	return list.Tags, nil
+}
+
+// token fetches an anonymous bearer token for a Bearer WWW-Authenticate challenge
+func (c *Client) token(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication %q", challenge)
+	}
+	attrs := parseChallenge(params)
+	realm, err := url.Parse(attrs["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid authentication realm %q", attrs["realm"])
+	}
+	q := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if attrs[key] != "" {
+			q.Set(key, attrs[key])
+		}
+	}
+	realm.RawQuery = q.Encode()
+	resp, err := c.get(ctx, realm, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request: %s", resp.Status)
+	}
+	var t struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", err
+	}
+	if t.Token != "" {
+		return t.Token, nil
+	}
+	if t.AccessToken != "" {
+		return t.AccessToken, nil
+	}
+	return "", fmt.Errorf("the token response holds no token")
+}
+
+// parseChallenge parses the key="value" pairs of a WWW-Authenticate challenge
+func parseChallenge(s string) map[string]string {
+	attrs := map[string]string{}
+	for s != "" {
+		key, rest, found := strings.Cut(strings.TrimLeft(s, " ,"), "=")
+		if !found {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		attrs[strings.ToLower(strings.TrimSpace(key))] = value
+		s = rest
+	}
+	return attrs
+}
+
+// nextPage returns the URL of the rel="next" Link header, resolved against the
+// current page, or nil on the last page
+func nextPage(current *url.URL, link string) (*url.URL, error) {
+	for _, l := range strings.Split(link, ",") {
+		target, params, _ := strings.Cut(strings.TrimSpace(l), ";")
+		if !strings.Contains(strings.ReplaceAll(params, " ", ""), `rel="next"`) {
+			continue
+		}
+		ref, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Link header %q: %w", link, err)
+		}
+		return current.ResolveReference(ref), nil
+	}
+	return nil, nil
+}
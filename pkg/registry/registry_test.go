@@ -0,0 +1,161 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const token = "anonymous-token"
+
+// fakeRegistry serves the tags of minc-org/minc in pages of two, behind a
+// bearer token when auth is set
+func fakeRegistry(t *testing.T, auth bool, tags []string) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("service") != "registry" || r.URL.Query().Get("scope") != "repository:minc-org/minc:pull" {
+			http.Error(w, "unexpected token request "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"token":%q}`, token)
+	})
+	mux.HandleFunc("/v2/minc-org/minc/tags/list", func(w http.ResponseWriter, r *http.Request) {
+		if auth && r.Header.Get("Authorization") != "Bearer "+token {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:minc-org/minc:pull"`, srv.URL))
+			http.Error(w, `{"errors":[{"code":"UNAUTHORIZED"}]}`, http.StatusUnauthorized)
+			return
+		}
+		page := tags
+		if last := r.URL.Query().Get("last"); last != "" {
+			for i, tag := range tags {
+				if tag == last {
+					page = tags[i+1:]
+				}
+			}
+		}
+		if len(page) > 2 {
+			page = page[:2]
+			w.Header().Set("Link", fmt.Sprintf(`</v2/minc-org/minc/tags/list?n=2&last=%s>; rel="next"`, page[1]))
+		}
+		fmt.Fprintf(w, `{"name":"minc-org/minc","tags":["%s"]}`, strings.Join(page, `","`))
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTags(t *testing.T) {
+	tags := []string{"4.18.0-okd-scos.5-amd64", "4.19.0-okd-scos.17-amd64", "4.19.0-okd-scos.17-arm64", "latest", "nightly-amd64"}
+	tests := []struct {
+		name string
+		auth bool
+		tags []string
+	}{
+		{name: "anonymous", tags: tags},
+		{name: "bearer token", auth: true, tags: tags},
+		{name: "single page", auth: true, tags: tags[:2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeRegistry(t, tt.auth, tt.tags)
+			repo := strings.TrimPrefix(srv.URL, "http://") + "/minc-org/minc"
+			got, err := New(true).Tags(context.Background(), repo)
+			if err != nil {
+				t.Fatalf("Tags: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.tags) {
+				t.Errorf("got %q, want %q", got, tt.tags)
+			}
+		})
+	}
+}
+
+func TestTagsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr string
+	}{
+		{
+			name: "not found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"errors":[{"code":"NAME_UNKNOWN"}]}`, http.StatusNotFound)
+			},
+			wantErr: "404 Not Found",
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			},
+			wantErr: "503 Service Unavailable",
+		},
+		{
+			name: "basic authentication",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+			wantErr: "unsupported authentication",
+		},
+		{
+			name: "token refused",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/token" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token"`, r.Host))
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+			wantErr: "token request: 403 Forbidden",
+		},
+		{
+			name: "token not accepted",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/token" {
+					fmt.Fprintf(w, `{"access_token":%q}`, token)
+					return
+				}
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token"`, r.Host))
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+			wantErr: "401 Unauthorized",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+			repo := strings.TrimPrefix(srv.URL, "http://") + "/minc-org/minc"
+			_, err := New(true).Tags(context.Background(), repo)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplitRepository(t *testing.T) {
+	tests := []struct {
+		repo, host, name string
+	}{
+		{"quay.io/minc-org/minc", "quay.io", "minc-org/minc"},
+		{"localhost/minc", "localhost", "minc"},
+		{"127.0.0.1:5000/minc-org/minc", "127.0.0.1:5000", "minc-org/minc"},
+		{"minc-org/minc", "registry-1.docker.io", "minc-org/minc"},
+		{"busybox", "registry-1.docker.io", "library/busybox"},
+	}
+	for _, tt := range tests {
+		if host, name := SplitRepository(tt.repo); host != tt.host || name != tt.name {
+			t.Errorf("SplitRepository(%q) = %q, %q, want %q, %q", tt.repo, host, name, tt.host, tt.name)
+		}
+	}
+}
@@ -1,30 +1,169 @@
 package retry
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
 	"time"
+)
+
+// Strategy selects how the delay between attempts grows
+type Strategy int
 
-	"github.com/minc-org/minc/pkg/log"
+const (
+	// Exponential multiplies the delay by Backoff.Multiplier after every attempt
+	Exponential Strategy = iota
+	// Linear sleeps InitialDelay * attempt
+	Linear
+	// Constant always sleeps InitialDelay
+	Constant
 )
 
-// Retry retries a function `fn` up to `maxRetries` times with exponential backoff.
-func Retry(fn func() error, maxRetries int, initialDelay time.Duration) error {
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		err := fn()
+const defaultMultiplier = 2
+
+// Backoff configures Do. The zero values of MaxAttempts, MaxElapsed and
+// MaxDelay mean no limit.
+type Backoff struct {
+	Strategy     Strategy
+	InitialDelay time.Duration
+	// MaxDelay caps the delay between two attempts
+	MaxDelay time.Duration
+	// Multiplier is the growth factor of Exponential, 2 if unset
+	Multiplier float64
+	// Jitter randomises each delay by up to this fraction, e.g. 0.2 for ±20%,
+	// between 0 and 1
+	Jitter float64
+	// MaxAttempts bounds the number of calls
+	MaxAttempts int
+	// MaxElapsed gives up once this much time has passed since the first call
+	MaxElapsed time.Duration
+	// OnAttempt is called after every failed attempt, e.g. to report progress
+	OnAttempt func(Attempt)
+}
+
+// Attempt describes a failed attempt passed to Backoff.OnAttempt
+type Attempt struct {
+	Number  int
+	Err     error
+	Elapsed time.Duration
+	// NextDelay is the sleep before the next attempt, zero if Do gives up
+	NextDelay time.Duration
+}
+
+// permanentError marks an error retrying can not fix, see Permanent
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps err so Do returns it right away instead of retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Do calls fn until it succeeds, returns a permanent error, the attempts or
+// elapsed time of b are used up, or ctx is done.
+func Do(ctx context.Context, b Backoff, fn func(ctx context.Context) error) error {
+	if !(b.Jitter >= 0 && b.Jitter <= 1) {
+		return fmt.Errorf("invalid backoff jitter %v, it must be between 0 and 1", b.Jitter)
+	}
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
 		if err == nil {
-			return nil // Success, exit retry loop
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var p *permanentError
+		if errors.As(err, &p) {
+			return p.err
 		}
 
-		log.Debug(fmt.Sprintf("Attempt: %d", attempt), "failed:", err)
+		elapsed := time.Since(start)
+		delay := b.delay(attempt)
+		var giveUp error
+		switch {
+		case b.MaxAttempts > 0 && attempt >= b.MaxAttempts:
+			giveUp = fmt.Errorf("operation failed after %d attempts: %w", attempt, err)
+		case b.MaxElapsed > 0 && elapsed+delay > b.MaxElapsed:
+			giveUp = fmt.Errorf("operation failed after %d attempts in %s: %w", attempt, elapsed.Round(time.Millisecond), err)
+		}
+		if giveUp != nil {
+			delay = 0
+		}
+		if b.OnAttempt != nil {
+			b.OnAttempt(Attempt{Number: attempt, Err: err, Elapsed: elapsed, NextDelay: delay})
+		}
+		if giveUp != nil {
+			return giveUp
+		}
 
-		if attempt < maxRetries {
-			sleepDuration := initialDelay * time.Duration(attempt) // Exponential backoff
-			log.Debug("Retrying in", "duration", sleepDuration)
-			time.Sleep(sleepDuration)
-		} else {
-			return errors.New("operation failed after multiple attempts: " + err.Error())
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
 		}
 	}
-	return nil
+}
+
+// delay returns the sleep after the given failed attempt, starting at 1
+func (b Backoff) delay(attempt int) time.Duration {
+	var d time.Duration
+	switch b.Strategy {
+	case Linear:
+		d = b.InitialDelay * time.Duration(attempt)
+	case Constant:
+		d = b.InitialDelay
+	default:
+		m := b.Multiplier
+		if m <= 0 {
+			m = defaultMultiplier
+		}
+		f := float64(b.InitialDelay)
+		for i := 1; i < attempt; i++ {
+			f *= m
+			if (b.MaxDelay > 0 && f > float64(b.MaxDelay)) || f > math.MaxInt64/2 {
+				break
+			}
+		}
+		d = duration(f)
+	}
+	if b.MaxDelay > 0 && d > b.MaxDelay {
+		d = b.MaxDelay
+	}
+	if b.Jitter > 0 {
+		d = duration(float64(d) * (1 + (rand.Float64()*2-1)*b.Jitter))
+	}
+	return d
+}
+
+// duration converts f to a Duration, clamped to the longest one
+func duration(f float64) time.Duration {
+	// float64(math.MaxInt64) rounds up to 2^63, which does not convert
+	if f >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(f)
 }
@@ -0,0 +1,243 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+func TestDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    []time.Duration
+	}{
+		{
+			name:    "exponential",
+			backoff: Backoff{InitialDelay: time.Second},
+			want:    []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:    "exponential multiplier",
+			backoff: Backoff{InitialDelay: time.Second, Multiplier: 3},
+			want:    []time.Duration{time.Second, 3 * time.Second, 9 * time.Second},
+		},
+		{
+			name:    "exponential capped",
+			backoff: Backoff{InitialDelay: time.Second, MaxDelay: 3 * time.Second},
+			want:    []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		{
+			name:    "linear",
+			backoff: Backoff{Strategy: Linear, InitialDelay: time.Second},
+			want:    []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:    "linear capped",
+			backoff: Backoff{Strategy: Linear, InitialDelay: time.Second, MaxDelay: 2 * time.Second},
+			want:    []time.Duration{time.Second, 2 * time.Second, 2 * time.Second},
+		},
+		{
+			name:    "constant",
+			backoff: Backoff{Strategy: Constant, InitialDelay: time.Second},
+			want:    []time.Duration{time.Second, time.Second, time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []time.Duration
+			for attempt := 1; attempt <= len(tt.want); attempt++ {
+				got = append(got, tt.backoff.delay(attempt))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got delays %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelayOverflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+	}{
+		{name: "exponential", backoff: Backoff{InitialDelay: time.Hour}},
+		// the last multiplication goes past math.MaxInt64, not only its half
+		{name: "multiplier 3", backoff: Backoff{InitialDelay: time.Hour, Multiplier: 3}},
+		{name: "jitter", backoff: Backoff{InitialDelay: time.Hour, Multiplier: 3, Jitter: 0.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if d := tt.backoff.delay(100); d <= 0 {
+				t.Errorf("got delay %v after 100 attempts, want a positive one", d)
+			}
+		})
+	}
+}
+
+func TestDelayJitter(t *testing.T) {
+	b := Backoff{Strategy: Constant, InitialDelay: time.Second, Jitter: 0.2}
+	varied := false
+	for range 100 {
+		d := b.delay(1)
+		if d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("got delay %v, want 1s ±20%%", d)
+		}
+		varied = varied || d != time.Second
+	}
+	if !varied {
+		t.Errorf("the jitter never changed the delay")
+	}
+}
+
+func TestDo(t *testing.T) {
+	const delay = time.Millisecond
+	tests := []struct {
+		name    string
+		backoff Backoff
+		// errs are the results of the calls, the last one repeats
+		errs      []error
+		wantCalls int
+		wantErr   error
+		// giveUp is set when Do stops after the attempts or elapsed time
+		giveUp bool
+	}{
+		{
+			name:      "success",
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "success after retries",
+			backoff:   Backoff{Strategy: Constant, InitialDelay: delay, MaxAttempts: 5},
+			errs:      []error{errTransient, errTransient, nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "attempts used up",
+			backoff:   Backoff{Strategy: Constant, InitialDelay: delay, MaxAttempts: 3},
+			errs:      []error{errTransient},
+			wantCalls: 3,
+			wantErr:   errTransient,
+			giveUp:    true,
+		},
+		{
+			// the next delay alone exceeds MaxElapsed, whatever the timer precision
+			name:      "elapsed time used up",
+			backoff:   Backoff{Strategy: Linear, InitialDelay: 20 * delay, MaxElapsed: 30 * delay},
+			errs:      []error{errTransient},
+			wantCalls: 2,
+			wantErr:   errTransient,
+			giveUp:    true,
+		},
+		{
+			name:      "permanent",
+			backoff:   Backoff{Strategy: Constant, InitialDelay: delay, MaxAttempts: 5},
+			errs:      []error{errTransient, Permanent(errTransient)},
+			wantCalls: 2,
+			wantErr:   errTransient,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var attempts []Attempt
+			tt.backoff.OnAttempt = func(a Attempt) { attempts = append(attempts, a) }
+			err := Do(context.Background(), tt.backoff, func(context.Context) error {
+				err := tt.errs[min(calls, len(tt.errs)-1)]
+				calls++
+				return err
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if IsPermanent(err) {
+				t.Errorf("Do returned the permanent wrapper %v", err)
+			}
+			// every failed call is reported, except a permanent one
+			wantAttempts := calls - 1
+			if tt.giveUp {
+				wantAttempts = calls
+			}
+			if len(attempts) != wantAttempts {
+				t.Fatalf("got %d attempts reported, want %d", len(attempts), wantAttempts)
+			}
+			for i, a := range attempts {
+				last := tt.giveUp && i == len(attempts)-1
+				if a.Number != i+1 || !errors.Is(a.Err, errTransient) || (a.NextDelay == 0) != last {
+					t.Errorf("attempt %d reported as %+v", i+1, a)
+				}
+			}
+		})
+	}
+}
+
+func TestDoInvalidJitter(t *testing.T) {
+	for _, jitter := range []float64{-0.1, 1.5, math.NaN()} {
+		calls := 0
+		err := Do(context.Background(), Backoff{Jitter: jitter}, func(context.Context) error {
+			calls++
+			return nil
+		})
+		if err == nil || calls != 0 {
+			t.Errorf("jitter %v: got error %v after %d calls, want an error before any call", jitter, err, calls)
+		}
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	tests := []struct {
+		name string
+		// cancelInCall cancels ctx from the call, otherwise before the delay
+		cancelInCall bool
+	}{
+		{name: "during the call", cancelInCall: true},
+		{name: "during the delay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			// the test times out if Do sleeps through the cancellation
+			b := Backoff{Strategy: Constant, InitialDelay: time.Hour}
+			if !tt.cancelInCall {
+				b.OnAttempt = func(Attempt) { cancel() }
+			}
+			calls := 0
+			err := Do(ctx, b, func(ctx context.Context) error {
+				calls++
+				if tt.cancelInCall {
+					cancel()
+				}
+				return errTransient
+			})
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("got %v, want %v", err, context.Canceled)
+			}
+			if calls != 1 {
+				t.Errorf("got %d calls, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	if Permanent(nil) != nil {
+		t.Errorf("Permanent(nil) is not nil")
+	}
+	err := Permanent(errTransient)
+	if !IsPermanent(err) || !errors.Is(err, errTransient) {
+		t.Errorf("Permanent(%v) = %v, want a permanent error wrapping it", errTransient, err)
+	}
+	if IsPermanent(errTransient) {
+		t.Errorf("IsPermanent(%v) is true", errTransient)
+	}
+}
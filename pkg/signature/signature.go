@@ -0,0 +1,154 @@
+// Package signature verifies cosign signatures of container images offline,
+// against a public key and a signature downloaded with
+// 'cosign download signature'.
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is returned when an image is unsigned or its signature
+// does not verify
+var ErrInvalidSignature = errors.New("invalid image signature")
+
+// Verification methods recorded in Result
+const (
+	MethodCosign = "cosign"
+	MethodPolicy = "policy"
+)
+
+// Result is the outcome of an image signature verification, recorded with
+// the cluster
+type Result struct {
+	Verified bool   `json:"verified"`
+	Method   string `json:"method,omitempty"`
+	Image    string `json:"image,omitempty"`
+	// Digest is the signed manifest digest, for cosign verifications
+	Digest string `json:"digest,omitempty"`
+	// Key is the public key of cosign verifications, Policy the policy file
+	Key    string     `json:"key,omitempty"`
+	Policy string     `json:"policy,omitempty"`
+	Time   *time.Time `json:"time,omitempty"`
+}
+
+// Bundle is a cosign signature as written by 'cosign download signature'
+type Bundle struct {
+	Base64Signature string
+	// Payload is the signed simple signing JSON, base64 encoded in the file
+	Payload []byte
+}
+
+// payload is the cosign simple signing payload
+type payload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+const payloadType = "cosign container image signature"
+
+// LoadBundle reads a signature bundle; 'cosign download signature' writes one
+// JSON object per signature, the first one is used.
+func LoadBundle(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var b Bundle
+	if err := json.NewDecoder(f).Decode(&b); err != nil {
+		return nil, fmt.Errorf("reading the signature bundle %s: %w", path, err)
+	}
+	if b.Base64Signature == "" || len(b.Payload) == 0 {
+		return nil, fmt.Errorf("%w: %s holds no signature", ErrInvalidSignature, path)
+	}
+	return &b, nil
+}
+
+// LoadPublicKey reads a PEM encoded ECDSA, RSA or Ed25519 public key
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM encoded public key", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the public key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// VerifyCosign verifies the bundle is signed by key and signs one of the
+// digests of the image in repository. It returns the signed digest.
+func VerifyCosign(key crypto.PublicKey, b *Bundle, repository string, digests []string) (string, error) {
+	sig, err := base64.StdEncoding.DecodeString(b.Base64Signature)
+	if err != nil {
+		return "", fmt.Errorf("%w: decoding the signature: %v", ErrInvalidSignature, err)
+	}
+	if err := verify(key, b.Payload, sig); err != nil {
+		return "", err
+	}
+	var p payload
+	if err := json.Unmarshal(b.Payload, &p); err != nil {
+		return "", fmt.Errorf("%w: parsing the signed payload: %v", ErrInvalidSignature, err)
+	}
+	if p.Critical.Type != payloadType {
+		return "", fmt.Errorf("%w: the payload type is %q, expected %q", ErrInvalidSignature, p.Critical.Type, payloadType)
+	}
+	if ref := p.Critical.Identity.DockerReference; ref != repository {
+		return "", fmt.Errorf("%w: the signature is for %s, not %s", ErrInvalidSignature, ref, repository)
+	}
+	signed := p.Critical.Image.DockerManifestDigest
+	if signed == "" {
+		return "", fmt.Errorf("%w: the payload signs no manifest digest", ErrInvalidSignature)
+	}
+	for _, d := range digests {
+		if _, digest, found := strings.Cut(d, "@"); found && digest == signed {
+			return signed, nil
+		}
+	}
+	return "", fmt.Errorf("%w: the signature is for %s, the image has the digests %s",
+		ErrInvalidSignature, signed, strings.Join(digests, ", "))
+}
+
+func verify(key crypto.PublicKey, message, sig []byte) error {
+	hash := sha256.Sum256(message)
+	var ok bool
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(k, hash[:], sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, message, sig)
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	if !ok {
+		return fmt.Errorf("%w: the signature does not match the public key", ErrInvalidSignature)
+	}
+	return nil
+}
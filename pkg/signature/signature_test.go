@@ -0,0 +1,172 @@
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const (
+	repository = "quay.io/minc-org/minc"
+	digest     = "sha256:5e0c5ed3e8a44a1a2f3d7f6c9b8a7e6d5c4b3a2918273645546372819a0b1c2d"
+)
+
+var repoDigests = []string{
+	"quay.io/minc-org/minc@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+	repository + "@" + digest,
+}
+
+// signer signs a payload like cosign does for its key type
+type signer struct {
+	name string
+	key  crypto.Signer
+}
+
+func (s signer) sign(t *testing.T, payload []byte) string {
+	t.Helper()
+	var sig []byte
+	var err error
+	switch s.key.(type) {
+	case ed25519.PrivateKey:
+		sig, err = s.key.Sign(rand.Reader, payload, crypto.Hash(0))
+	default:
+		hash := sha256.Sum256(payload)
+		sig, err = s.key.Sign(rand.Reader, hash[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func signers(t *testing.T) []signer {
+	t.Helper()
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []signer{{"ecdsa", ecKey}, {"rsa", rsaKey}, {"ed25519", edKey}}
+}
+
+func signedPayload(t *testing.T, reference, manifestDigest string) []byte {
+	t.Helper()
+	var p payload
+	p.Critical.Identity.DockerReference = reference
+	p.Critical.Image.DockerManifestDigest = manifestDigest
+	p.Critical.Type = payloadType
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestVerifyCosign(t *testing.T) {
+	keys := signers(t)
+	tests := []struct {
+		name string
+		// bundle returns the bundle to verify and the key to verify it with
+		bundle  func(t *testing.T, s, other signer) (*Bundle, crypto.PublicKey)
+		digests []string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			bundle: func(t *testing.T, s, _ signer) (*Bundle, crypto.PublicKey) {
+				p := signedPayload(t, repository, digest)
+				return &Bundle{Base64Signature: s.sign(t, p), Payload: p}, s.key.Public()
+			},
+		},
+		{
+			name: "tampered payload",
+			bundle: func(t *testing.T, s, _ signer) (*Bundle, crypto.PublicKey) {
+				p := signedPayload(t, repository, digest)
+				sig := s.sign(t, p)
+				return &Bundle{Base64Signature: sig, Payload: signedPayload(t, repository, repoDigests[0][len(repository)+1:])}, s.key.Public()
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong key",
+			bundle: func(t *testing.T, s, other signer) (*Bundle, crypto.PublicKey) {
+				p := signedPayload(t, repository, digest)
+				return &Bundle{Base64Signature: s.sign(t, p), Payload: p}, other.key.Public()
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong reference",
+			bundle: func(t *testing.T, s, _ signer) (*Bundle, crypto.PublicKey) {
+				p := signedPayload(t, "quay.io/attacker/minc", digest)
+				return &Bundle{Base64Signature: s.sign(t, p), Payload: p}, s.key.Public()
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong digest",
+			bundle: func(t *testing.T, s, _ signer) (*Bundle, crypto.PublicKey) {
+				p := signedPayload(t, repository, "sha256:ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+				return &Bundle{Base64Signature: s.sign(t, p), Payload: p}, s.key.Public()
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty digest",
+			bundle: func(t *testing.T, s, _ signer) (*Bundle, crypto.PublicKey) {
+				p := signedPayload(t, repository, "")
+				return &Bundle{Base64Signature: s.sign(t, p), Payload: p}, s.key.Public()
+			},
+			// a repo digest without "@" must not match the empty digest
+			digests: []string{repository},
+			wantErr: true,
+		},
+		{
+			name: "invalid base64",
+			bundle: func(t *testing.T, s, _ signer) (*Bundle, crypto.PublicKey) {
+				return &Bundle{Base64Signature: "not base64!", Payload: signedPayload(t, repository, digest)}, s.key.Public()
+			},
+			wantErr: true,
+		},
+	}
+	for i, s := range keys {
+		other := keys[(i+1)%len(keys)]
+		for _, tt := range tests {
+			t.Run(s.name+"/"+tt.name, func(t *testing.T) {
+				b, key := tt.bundle(t, s, other)
+				digests := tt.digests
+				if digests == nil {
+					digests = repoDigests
+				}
+				signed, err := VerifyCosign(key, b, repository, digests)
+				if tt.wantErr {
+					if !errors.Is(err, ErrInvalidSignature) {
+						t.Errorf("got %v, want %v", err, ErrInvalidSignature)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if signed != digest {
+					t.Errorf("got digest %s, want %s", signed, digest)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Package snapshot keeps track of cluster snapshots. A snapshot is the
+// MicroShift container committed as an image, plus tar archives of the
+// volumes it mounts, stored with their metadata in the minc config directory.
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/minc-org/minc/pkg/minc/types"
+)
+
+const (
+	// imageRepository holds the committed snapshot images, tagged with the snapshot name
+	imageRepository = "localhost/minc-snapshot"
+	metadataFile    = "snapshot.json"
+	volumesDir      = "volumes"
+)
+
+// ErrNotFound is returned for snapshots minc does not know about
+var ErrNotFound = errors.New("snapshot not found")
+
+// names are used as image tags
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,127}$`)
+
+// Snapshot is the metadata of a snapshot
+type Snapshot struct {
+	Name     string    `json:"name"`
+	Created  time.Time `json:"created"`
+	Provider string    `json:"provider"`
+	// Image is the committed MicroShift container
+	Image string `json:"image"`
+	// SourceImage is the image the snapshotted cluster ran
+	SourceImage string `json:"sourceImage"`
+	// Cluster are the settings the cluster is recreated with
+	Cluster *types.CreateType `json:"cluster"`
+	Volumes []Volume          `json:"volumes,omitempty"`
+}
+
+// Volume is an archived volume, restored into the volume mounted at
+// Destination in the recreated container
+type Volume struct {
+	Destination string `json:"destination"`
+	// Archive is the tar archive's file name in the snapshot's volumes directory
+	Archive string `json:"archive"`
+	Size    int64  `json:"size"`
+}
+
+// ValidateName checks name can be used for a snapshot
+func ValidateName(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: use up to 128 letters, digits, '_', '.' and '-', starting with a letter or digit", name)
+	}
+	return nil
+}
+
+// Image returns the image a snapshot named name is committed to
+func Image(name string) string {
+	return fmt.Sprintf("%s:%s", imageRepository, name)
+}
+
+// Dir returns the directory holding all snapshots
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "minc", "snapshots"), nil
+}
+
+// path returns the directory of the snapshot named name
+func path(name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// VolumesDir returns the directory holding the volume archives of the
+// snapshot named name, creating it
+func VolumesDir(name string) (string, error) {
+	p, err := path(name)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(p, volumesDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// ArchivePath returns the path of v's archive in the snapshot s
+func (s *Snapshot) ArchivePath(v Volume) (string, error) {
+	p, err := path(s.Name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(p, volumesDir, v.Archive), nil
+}
+
+// Exists reports whether a snapshot named name is recorded
+func Exists(name string) bool {
+	_, err := Load(name)
+	return err == nil
+}
+
+// Load reads the metadata of the snapshot named name
+func Load(name string) (*Snapshot, error) {
+	p, err := path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(p, metadataFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("reading snapshot %s: %w", name, err)
+	}
+	return &s, nil
+}
+
+// Save writes the metadata of s, the snapshot is complete once it is saved
+func Save(s *Snapshot) error {
+	p, err := path(s.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(p, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(p, metadataFile), data, 0600)
+}
+
+// List returns the recorded snapshots, oldest first. Incomplete snapshots,
+// without metadata, are skipped.
+func List() ([]*Snapshot, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []*Snapshot
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		s, err := Load(e.Name())
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, s)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+	return snapshots, nil
+}
+
+// Remove deletes the metadata and volume archives of the snapshot named name
+func Remove(name string) error {
+	p, err := path(name)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(p)
+}
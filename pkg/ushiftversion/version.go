@@ -0,0 +1,116 @@
+// Package ushiftversion parses MicroShift image versions, such as
+// 4.19.0-okd-scos.17, and checks which upgrades between them are supported.
+package ushiftversion
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// versionRegexp matches <major>.<minor>.<patch> with an optional
+// -<stream>.<build> suffix
+var versionRegexp = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)(?:-(.+)\.(\d+))?$`)
+
+// Version is a parsed MicroShift version
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	// Stream is the build stream, e.g. okd-scos, and Build the build number in it
+	Stream string
+	Build  int
+	raw    string
+}
+
+// Parse parses a MicroShift version
+func Parse(s string) (*Version, error) {
+	m := versionRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid MicroShift version %q, expected e.g. 4.19.0-okd-scos.17", s)
+	}
+	v := &Version{Stream: m[4], raw: s}
+	// the regexp only matches digits, Atoi can only fail on overflow
+	for i, field := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid MicroShift version %q: %w", s, err)
+		}
+		*field = n
+	}
+	if m[5] != "" {
+		n, err := strconv.Atoi(m[5])
+		if err != nil {
+			return nil, fmt.Errorf("invalid MicroShift version %q: %w", s, err)
+		}
+		v.Build = n
+	}
+	return v, nil
+}
+
+func (v *Version) String() string {
+	return v.raw
+}
+
+// Compare returns -1, 0 or 1 when v is older, the same or newer than o.
+// Versions of different streams are ordered by stream name after the
+// release numbers.
+func (v *Version) Compare(o *Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	if v.Stream != o.Stream {
+		if v.Stream < o.Stream {
+			return -1
+		}
+		return 1
+	}
+	return sign(v.Build - o.Build)
+}
+
+func sign(d int) int {
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	}
+	return 0
+}
+
+// CheckUpgrade returns an error if MicroShift can not upgrade its data from
+// one version to the other: downgrades, major upgrades and skipping a minor
+// version are not supported, except from an even minor (EUS) release to the
+// next even one.
+func CheckUpgrade(from, to *Version) error {
+	if from.Stream != to.Stream {
+		return fmt.Errorf("can not upgrade from the %s stream to the %s stream", streamName(from), streamName(to))
+	}
+	switch c := to.Compare(from); {
+	case c == 0:
+		return fmt.Errorf("the cluster already runs MicroShift %s", from)
+	case c < 0:
+		return fmt.Errorf("downgrading MicroShift from %s to %s is not supported", from, to)
+	}
+	if to.Major != from.Major {
+		return fmt.Errorf("upgrading MicroShift across major versions (%s to %s) is not supported", from, to)
+	}
+	switch skew := to.Minor - from.Minor; {
+	case skew <= 1:
+		return nil
+	case skew == 2 && from.Minor%2 == 0:
+		return nil
+	default:
+		return fmt.Errorf("upgrading MicroShift from %d.%d to %d.%d skips a minor version, upgrade to %d.%d first",
+			from.Major, from.Minor, to.Major, to.Minor, from.Major, from.Minor+1)
+	}
+}
+
+func streamName(v *Version) string {
+	if v.Stream == "" {
+		return "release"
+	}
+	return v.Stream
+}
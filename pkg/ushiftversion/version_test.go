@@ -0,0 +1,50 @@
+package ushiftversion
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckUpgrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr string
+	}{
+		{name: "next build", from: "4.19.0-okd-scos.17", to: "4.19.0-okd-scos.18"},
+		{name: "next patch", from: "4.19.0-okd-scos.17", to: "4.19.1-okd-scos.1"},
+		{name: "next minor", from: "4.19.0-okd-scos.17", to: "4.20.0-okd-scos.1"},
+		{name: "even to even+2 (EUS)", from: "4.18.3-okd-scos.2", to: "4.20.0-okd-scos.1"},
+		{name: "release stream", from: "4.19.0", to: "4.20.1"},
+		{name: "same version", from: "4.19.0-okd-scos.17", to: "4.19.0-okd-scos.17", wantErr: "already runs"},
+		{name: "downgrade", from: "4.19.0-okd-scos.17", to: "4.19.0-okd-scos.16", wantErr: "downgrading"},
+		{name: "minor downgrade", from: "4.20.0-okd-scos.1", to: "4.19.0-okd-scos.17", wantErr: "downgrading"},
+		{name: "major bump", from: "4.19.0-okd-scos.17", to: "5.0.0-okd-scos.1", wantErr: "across major versions"},
+		{name: "odd to odd+2", from: "4.19.0-okd-scos.17", to: "4.21.0-okd-scos.1", wantErr: "upgrade to 4.20 first"},
+		{name: "even to even+4", from: "4.18.0-okd-scos.5", to: "4.22.0-okd-scos.1", wantErr: "skips a minor version"},
+		{name: "different streams", from: "4.19.0-okd-scos.17", to: "4.20.0-ec.3", wantErr: "from the okd-scos stream to the ec stream"},
+		{name: "release to stream", from: "4.19.0", to: "4.19.0-okd-scos.17", wantErr: "from the release stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, err := Parse(tt.from)
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, err := Parse(tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = CheckUpgrade(from, to)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("got no error, want one containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("got %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}